@@ -1,3 +1,13 @@
-module main
+module github.com/cazzano/password_manager_cli
 
 go 1.24.5
+
+require (
+	filippo.io/age v1.2.1
+	golang.org/x/crypto v0.36.0
+	golang.org/x/image v0.25.0
+	golang.org/x/term v0.30.0
+	rsc.io/qr v0.2.0
+)
+
+require golang.org/x/sys v0.31.0
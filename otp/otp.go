@@ -0,0 +1,250 @@
+// Package otp implements the HOTP/TOTP primitives used by the password
+// manager, along with the pieces needed to act as an issuer: random secret
+// provisioning and validation of submitted codes with replay protection.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinSecretSize is the smallest secret GenerateSecret will produce. RFC 4226
+// requires at least 128 bits and recommends 160.
+const MinSecretSize = 16
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Value runs the HMAC-SHA1 and dynamic truncation steps of RFC 4226 for a
+// single counter and returns the 31-bit truncated value.
+func Value(key []byte, counter uint64) uint32 {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, counter)
+
+	h := hmac.New(sha1.New, key)
+	h.Write(buf)
+	hash := h.Sum(nil)
+
+	offset := int(hash[len(hash)-1] & 0x0f)
+	return binary.BigEndian.Uint32(hash[offset:offset+4]) & 0x7fffffff
+}
+
+// Code formats the HOTP value for counter as a zero-padded decimal string.
+func Code(key []byte, counter uint64, digits int) string {
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, Value(key, counter)%mod)
+}
+
+// Counter returns the TOTP time step containing t.
+func Counter(t time.Time, period int) int64 {
+	return t.Unix() / int64(period)
+}
+
+// GenerateSecret returns size cryptographically random bytes encoded as
+// unpadded base32, the form authenticator apps expect.
+func GenerateSecret(size int) (string, error) {
+	if size < MinSecretSize {
+		return "", fmt.Errorf("secret size must be at least %d bytes", MinSecretSize)
+	}
+
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate random secret: %v", err)
+	}
+
+	return secretEncoding.EncodeToString(key), nil
+}
+
+// KeyURI builds the otpauth:// URI that authenticator apps import, usually
+// through a QR code.
+func KeyURI(issuer, accountName, secret string, period, digits int) string {
+	label := accountName
+	if issuer != "" {
+		label = issuer + ":" + accountName
+	}
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	if issuer != "" {
+		query.Set("issuer", issuer)
+	}
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(digits))
+	query.Set("period", strconv.Itoa(period))
+
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// Key holds the parameters of an otpauth:// URI.
+type Key struct {
+	Type        string // totp or hotp
+	Issuer      string
+	AccountName string
+	Secret      string
+	Algorithm   string
+	Digits      int
+	Period      int
+	Encoder     string // "steam" for Steam Guard codes, as written by KeePassXC
+}
+
+// ParseKeyURI parses an otpauth:// URI as written by KeyURI and by
+// authenticator apps. Missing parameters get the defaults of the Key URI
+// format: SHA1, 6 digits and a 30 second period.
+func ParseKeyURI(uri string) (*Key, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %v", err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("not an otpauth URI: %s", u.Scheme)
+	}
+
+	query := u.Query()
+	key := &Key{
+		Type:      strings.ToLower(u.Host),
+		Issuer:    query.Get("issuer"),
+		Secret:    strings.ToUpper(query.Get("secret")),
+		Algorithm: strings.ToUpper(query.Get("algorithm")),
+		Encoder:   strings.ToLower(query.Get("encoder")),
+		Digits:    6,
+		Period:    30,
+	}
+	if key.Type != "totp" && key.Type != "hotp" {
+		return nil, fmt.Errorf("unknown OTP type '%s'", u.Host)
+	}
+	if key.Secret == "" {
+		return nil, fmt.Errorf("otpauth URI has no secret")
+	}
+	if key.Algorithm == "" {
+		key.Algorithm = "SHA1"
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		if key.Issuer == "" {
+			key.Issuer = strings.TrimSpace(issuer)
+		}
+		label = account
+	}
+	key.AccountName = strings.TrimSpace(label)
+
+	for name, field := range map[string]*int{"digits": &key.Digits, "period": &key.Period} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid %s '%s' in otpauth URI", name, value)
+			}
+			*field = n
+		}
+	}
+
+	return key, nil
+}
+
+// CounterStore remembers the last time step accepted for each entry so a
+// code can't be replayed. Implementations must be safe for concurrent use.
+type CounterStore interface {
+	LastCounter(id string) (counter int64, ok bool, err error)
+	SetLastCounter(id string, counter int64) error
+}
+
+// MemoryStore is a CounterStore that keeps counters in memory.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]int64)}
+}
+
+func (m *MemoryStore) LastCounter(id string) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counter, ok := m.counters[id]
+	return counter, ok, nil
+}
+
+func (m *MemoryStore) SetLastCounter(id string, counter int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[id] = counter
+	return nil
+}
+
+// Validator checks codes submitted to an issuer.
+type Validator struct {
+	Period int // Time step in seconds, 30 if zero
+	Digits int // Code length, 6 if zero
+	Skew   int // Number of periods accepted either side of the current one
+	Store  CounterStore
+
+	mu sync.Mutex
+}
+
+// NewValidator returns a Validator with the usual defaults: 30 second
+// periods, 6 digits and one period of drift either way.
+func NewValidator(store CounterStore) *Validator {
+	return &Validator{Period: 30, Digits: 6, Skew: 1, Store: store}
+}
+
+// Validate reports whether code is valid for the base32 secret at t. A code
+// is accepted at most once: the matching counter must be newer than the last
+// one accepted for id, and it is recorded on success.
+func (v *Validator) Validate(id, secret, code string, t time.Time) (bool, error) {
+	period := v.Period
+	if period <= 0 {
+		period = 30
+	}
+	digits := v.Digits
+	if digits <= 0 {
+		digits = 6
+	}
+	if len(code) != digits {
+		return false, nil
+	}
+
+	key, err := secretEncoding.DecodeString(strings.TrimRight(strings.ToUpper(secret), "="))
+	if err != nil {
+		return false, fmt.Errorf("invalid secret: %v", err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	last, seen, err := v.Store.LastCounter(id)
+	if err != nil {
+		return false, err
+	}
+
+	current := Counter(t, period)
+	for delta := -v.Skew; delta <= v.Skew; delta++ {
+		counter := current + int64(delta)
+		if counter < 0 || (seen && counter <= last) {
+			continue
+		}
+
+		expected := Code(key, uint64(counter), digits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			if err := v.Store.SetLastCounter(id, counter); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
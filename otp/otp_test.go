@@ -0,0 +1,177 @@
+package otp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rfcKey = []byte("12345678901234567890")
+
+// TestValue checks the HOTP values and codes from RFC 4226, appendix D.
+func TestValue(t *testing.T) {
+	tests := []struct {
+		value uint32
+		code  string
+	}{
+		{0x4c93cf18, "755224"},
+		{0x41397eea, "287082"},
+		{0x082fef30, "359152"},
+		{0x66ef7655, "969429"},
+		{0x61c5938a, "338314"},
+		{0x33c083d4, "254676"},
+		{0x7256c032, "287922"},
+		{0x04e5b397, "162583"},
+		{0x2823443f, "399871"},
+		{0x2679dc69, "520489"},
+	}
+
+	for counter, tt := range tests {
+		if got := Value(rfcKey, uint64(counter)); got != tt.value {
+			t.Errorf("Value(%d) = %#x, want %#x", counter, got, tt.value)
+		}
+		if got := Code(rfcKey, uint64(counter), 6); got != tt.code {
+			t.Errorf("Code(%d) = %s, want %s", counter, got, tt.code)
+		}
+	}
+}
+
+// TestTOTP checks the SHA1 vectors from RFC 6238, appendix B.
+func TestTOTP(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		counter := Counter(time.Unix(tt.unix, 0), 30)
+		if got := Code(rfcKey, uint64(counter), 8); got != tt.code {
+			t.Errorf("at %d: got %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	if _, err := GenerateSecret(MinSecretSize - 1); err == nil {
+		t.Error("secret below the minimum size was accepted")
+	}
+
+	secret, err := GenerateSecret(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("%s decodes to %d bytes (%v), want 20", secret, len(key), err)
+	}
+}
+
+func TestKeyURIRoundTrip(t *testing.T) {
+	uri := KeyURI("Example Co", "alice@example.com", "JBSWY3DPEHPK3PXP", 60, 8)
+	key, err := ParseKeyURI(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Key{Type: "totp", Issuer: "Example Co", AccountName: "alice@example.com",
+		Secret: "JBSWY3DPEHPK3PXP", Algorithm: "SHA1", Digits: 8, Period: 60}
+	if *key != want {
+		t.Errorf("got %+v, want %+v", *key, want)
+	}
+
+	// The issuer is taken from the label prefix when the parameter is missing.
+	key, err = ParseKeyURI("otpauth://totp/Example:bob?secret=jbswy3dpehpk3pxp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Issuer != "Example" || key.AccountName != "bob" || key.Secret != "JBSWY3DPEHPK3PXP" || key.Digits != 6 || key.Period != 30 {
+		t.Errorf("got %+v", *key)
+	}
+
+	key, err = ParseKeyURI(KeyURI("Steam", "gabe", "JBSWY3DPEHPK3PXP", 30, 5) + "&encoder=steam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Encoder != "steam" || key.Digits != 5 || key.Issuer != "Steam" {
+		t.Errorf("got %+v", *key)
+	}
+
+	for _, bad := range []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://push/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/alice",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=0",
+	} {
+		if _, err := ParseKeyURI(bad); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+func TestValidatorRejectsReplay(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+	code := Code(rfcKey, uint64(Counter(now, 30)), 6)
+
+	v := NewValidator(NewMemoryStore())
+	if ok, err := v.Validate("id", secret, code, now); err != nil || !ok {
+		t.Fatalf("first use: ok=%v, err=%v", ok, err)
+	}
+	if ok, _ := v.Validate("id", secret, code, now); ok {
+		t.Error("code was accepted twice")
+	}
+
+	// A code from an earlier step is refused once a later one was used.
+	earlier := Code(rfcKey, uint64(Counter(now, 30)-1), 6)
+	if ok, _ := v.Validate("id", secret, earlier, now); ok {
+		t.Error("older code was accepted after a newer one")
+	}
+
+	// Counters are kept per id.
+	if ok, _ := v.Validate("other", secret, code, now); !ok {
+		t.Error("code was refused for a different id")
+	}
+}
+
+func TestValidatorSkew(t *testing.T) {
+	secret := strings.TrimRight(base32.StdEncoding.EncodeToString(rfcKey), "=")
+	now := time.Unix(1111111111, 0)
+	current := Counter(now, 30)
+
+	tests := []struct {
+		skew  int
+		delta int64
+		ok    bool
+	}{
+		{0, 0, true},
+		{0, -1, false},
+		{0, 1, false},
+		{1, -1, true},
+		{1, 1, true},
+		{1, -2, false},
+		{1, 2, false},
+		{2, -2, true},
+		{2, 2, true},
+		{2, 3, false},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(NewMemoryStore())
+		v.Skew = tt.skew
+		code := Code(rfcKey, uint64(current+tt.delta), 6)
+		ok, err := v.Validate("id", secret, code, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.ok {
+			t.Errorf("skew %d, step %+d: ok=%v, want %v", tt.skew, tt.delta, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// KeePass databases default to Argon2d, which golang.org/x/crypto/argon2
+// does not expose (it only offers Argon2i and Argon2id). This is Argon2d
+// version 0x13 as specified in RFC 9106.
+const (
+	argon2BlockWords = 128 // 1 KiB blocks of 64-bit words
+	argon2SyncPoints = 4
+	argon2Version    = 0x13
+	argon2TypeD      = 0
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+// argon2dKey derives keyLen bytes from password and salt using memory KiB,
+// time passes and threads lanes. secret and data are the optional K and X
+// inputs of the RFC.
+func argon2dKey(password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	lanes := uint32(threads)
+	h0 := argon2InitialHash(password, salt, secret, data, time, memory, lanes, keyLen)
+
+	segmentLength := max(memory/(argon2SyncPoints*lanes), 2)
+	laneLength := segmentLength * argon2SyncPoints
+	blocks := make([]argon2Block, laneLength*lanes)
+
+	var seed [blake2b.Size + 8]byte
+	copy(seed[:], h0[:])
+	var blockBytes [1024]byte
+	for lane := uint32(0); lane < lanes; lane++ {
+		binary.LittleEndian.PutUint32(seed[blake2b.Size+4:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(seed[blake2b.Size:], i)
+			argon2Hash(blockBytes[:], seed[:])
+			block := &blocks[lane*laneLength+i]
+			for w := range block {
+				block[w] = binary.LittleEndian.Uint64(blockBytes[w*8:])
+			}
+		}
+	}
+
+	for pass := uint32(0); pass < time; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			for lane := uint32(0); lane < lanes; lane++ {
+				start := uint32(0)
+				if pass == 0 && slice == 0 {
+					start = 2
+				}
+				for index := start; index < segmentLength; index++ {
+					offset := lane*laneLength + slice*segmentLength + index
+					prev := offset - 1
+					if offset%laneLength == 0 {
+						prev = offset + laneLength - 1
+					}
+
+					rand := blocks[prev][0]
+					ref := argon2RefIndex(rand, pass, slice, lane, index, lanes, segmentLength, laneLength)
+					argon2Compress(&blocks[offset], &blocks[prev], &blocks[ref], pass > 0)
+				}
+			}
+		}
+	}
+
+	final := blocks[laneLength-1]
+	for lane := uint32(1); lane < lanes; lane++ {
+		last := &blocks[lane*laneLength+laneLength-1]
+		for w := range final {
+			final[w] ^= last[w]
+		}
+	}
+	for w, v := range final {
+		binary.LittleEndian.PutUint64(blockBytes[w*8:], v)
+	}
+
+	key := make([]byte, keyLen)
+	argon2Hash(key, blockBytes[:])
+	return key
+}
+
+func argon2InitialHash(password, salt, secret, data []byte, time, memory, lanes, keyLen uint32) [blake2b.Size]byte {
+	h, _ := blake2b.New512(nil)
+	var word [4]byte
+	writeWord := func(v uint32) {
+		binary.LittleEndian.PutUint32(word[:], v)
+		h.Write(word[:])
+	}
+	for _, v := range []uint32{lanes, keyLen, memory, time, argon2Version, argon2TypeD} {
+		writeWord(v)
+	}
+	for _, input := range [][]byte{password, salt, secret, data} {
+		writeWord(uint32(len(input)))
+		h.Write(input)
+	}
+
+	var sum [blake2b.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// argon2Hash is the variable-length hash H' of the RFC.
+func argon2Hash(out, in []byte) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(out)))
+
+	if len(out) <= blake2b.Size {
+		h, _ := blake2b.New(len(out), nil)
+		h.Write(length[:])
+		h.Write(in)
+		h.Sum(out[:0])
+		return
+	}
+
+	h, _ := blake2b.New512(nil)
+	h.Write(length[:])
+	h.Write(in)
+	v := h.Sum(nil)
+	copy(out, v[:32])
+	out = out[32:]
+	for len(out) > blake2b.Size {
+		sum := blake2b.Sum512(v)
+		v = sum[:]
+		copy(out, v[:32])
+		out = out[32:]
+	}
+	h, _ = blake2b.New(len(out), nil)
+	h.Write(v)
+	h.Sum(out[:0])
+}
+
+// argon2RefIndex maps the pseudo-random word of the previous block to the
+// reference block, following section 3.4 of the RFC.
+func argon2RefIndex(rand uint64, pass, slice, lane, index, lanes, segmentLength, laneLength uint32) uint32 {
+	refLane := uint32(rand>>32) % lanes
+	if pass == 0 && slice == 0 {
+		refLane = lane
+	}
+
+	var area, start uint32
+	if pass == 0 {
+		area = slice * segmentLength
+	} else {
+		area = laneLength - segmentLength
+		start = ((slice + 1) % argon2SyncPoints) * segmentLength
+	}
+	if refLane == lane {
+		area += index
+	}
+	if index == 0 || refLane == lane {
+		area--
+	}
+
+	x := (rand & 0xffffffff) * (rand & 0xffffffff) >> 32
+	y := uint64(area) * x >> 32
+	relative := uint64(area) - 1 - y
+	return refLane*laneLength + uint32((uint64(start)+relative)%uint64(laneLength))
+}
+
+// argon2Compress sets out to G(prev, ref), XORed into out's old contents
+// on passes after the first.
+func argon2Compress(out, prev, ref *argon2Block, xorOld bool) {
+	var r, z argon2Block
+	for i := range r {
+		r[i] = prev[i] ^ ref[i]
+	}
+	z = r
+
+	for i := 0; i < argon2BlockWords; i += 16 {
+		argon2Permute(&z[i], &z[i+1], &z[i+2], &z[i+3], &z[i+4], &z[i+5], &z[i+6], &z[i+7],
+			&z[i+8], &z[i+9], &z[i+10], &z[i+11], &z[i+12], &z[i+13], &z[i+14], &z[i+15])
+	}
+	for i := 0; i < 16; i += 2 {
+		argon2Permute(&z[i], &z[i+1], &z[i+16], &z[i+17], &z[i+32], &z[i+33], &z[i+48], &z[i+49],
+			&z[i+64], &z[i+65], &z[i+80], &z[i+81], &z[i+96], &z[i+97], &z[i+112], &z[i+113])
+	}
+
+	for i := range out {
+		if xorOld {
+			out[i] ^= z[i] ^ r[i]
+		} else {
+			out[i] = z[i] ^ r[i]
+		}
+	}
+}
+
+// argon2Permute is the BLAKE2b round P with multiplication-hardened
+// additions.
+func argon2Permute(v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 *uint64) {
+	argon2Mix(v0, v4, v8, v12)
+	argon2Mix(v1, v5, v9, v13)
+	argon2Mix(v2, v6, v10, v14)
+	argon2Mix(v3, v7, v11, v15)
+	argon2Mix(v0, v5, v10, v15)
+	argon2Mix(v1, v6, v11, v12)
+	argon2Mix(v2, v7, v8, v13)
+	argon2Mix(v3, v4, v9, v14)
+}
+
+func argon2Mix(a, b, c, d *uint64) {
+	fBlaMka := func(x, y uint64) uint64 {
+		return x + y + 2*uint64(uint32(x))*uint64(uint32(y))
+	}
+	*a = fBlaMka(*a, *b)
+	*d = bits.RotateLeft64(*d^*a, -32)
+	*c = fBlaMka(*c, *d)
+	*b = bits.RotateLeft64(*b^*c, -24)
+	*a = fBlaMka(*a, *b)
+	*d = bits.RotateLeft64(*d^*a, -16)
+	*c = fBlaMka(*c, *d)
+	*b = bits.RotateLeft64(*b^*c, -63)
+}
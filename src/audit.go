@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	severityHigh   = "high"
+	severityMedium = "medium"
+	severityLow    = "low"
+
+	minTOTPSecretBits = 128 // RFC 4226 section 4 requires at least 128 bits
+)
+
+// AuditFinding is one problem found by AuditVault. Secrets themselves are
+// never included.
+type AuditFinding struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"` // password, pin or mfa
+	Name     string `json:"name"`
+	Account  string `json:"account"`
+	Issue    string `json:"issue"`
+	Detail   string `json:"detail"`
+}
+
+type AuditReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Passwords   int            `json:"passwords_checked"`
+	PINs        int            `json:"pins_checked"`
+	MFA         int            `json:"mfa_checked"`
+	Findings    []AuditFinding `json:"findings"`
+	Undated     []string       `json:"undated_passwords,omitempty"` // Passwords with no change date, not checked for age
+}
+
+// AuditOptions controls the thresholds used by AuditVault.
+type AuditOptions struct {
+	MaxAge   time.Duration // Passwords not changed for longer are flagged
+	MinScore int           // Passwords with a lower estimateStrength score are flagged
+	FailOn   string        // Lowest severity counted by AuditVault's return value
+}
+
+var severityOrder = map[string]int{severityHigh: 0, severityMedium: 1, severityLow: 2}
+
+// buildAuditReport scans every stored password, PIN and MFA secret.
+func buildAuditReport(opts AuditOptions) (*AuditReport, error) {
+	passwords, err := loadPasswordStorage()
+	if err != nil {
+		return nil, fmt.Errorf("error loading passwords: %v", err)
+	}
+	pins, err := loadMPINConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading MPINs: %v", err)
+	}
+	mfa, err := loadMFAStorage()
+	if err != nil {
+		return nil, fmt.Errorf("error loading MFA entries: %v", err)
+	}
+
+	now := time.Now()
+	report := &AuditReport{
+		GeneratedAt: now,
+		Passwords:   len(passwords.Entries),
+		PINs:        len(pins.Entries),
+		MFA:         len(mfa.Entries),
+		Findings:    []AuditFinding{},
+	}
+	add := func(severity, kind, name, account, issue, detail string) {
+		report.Findings = append(report.Findings, AuditFinding{severity, kind, name, account, issue, detail})
+	}
+
+	users := make(map[string][]string)
+	for _, entry := range passwords.Entries {
+		label := fmt.Sprintf("%s (%s)", entry.Name, entry.Account)
+		users[entry.Password] = append(users[entry.Password], label)
+	}
+
+	for _, entry := range passwords.Entries {
+		label := fmt.Sprintf("%s (%s)", entry.Name, entry.Account)
+
+		if shared := users[entry.Password]; len(shared) > 1 {
+			var others []string
+			for _, other := range shared {
+				if other != label {
+					others = append(others, other)
+				}
+			}
+			add(severityHigh, "password", entry.Name, entry.Account, "reused", "same password as "+strings.Join(others, ", "))
+		}
+
+		strength := estimateStrength(entry.Password, entry.Name, entry.Account)
+		if strength.Score < opts.MinScore {
+			severity := severityMedium
+			if strength.Score <= 1 {
+				severity = severityHigh
+			}
+			detail := fmt.Sprintf("score %d/4, crack time %s offline", strength.Score, displayCrackTime(strength.CrackTimeOffline))
+			if strength.Warning != "" {
+				detail += ": " + strength.Warning
+			}
+			add(severity, "password", entry.Name, entry.Account, "weak", detail)
+		}
+
+		if entry.UpdatedAt == nil {
+			report.Undated = append(report.Undated, label)
+		} else if age := now.Sub(*entry.UpdatedAt); age > opts.MaxAge {
+			add(severityLow, "password", entry.Name, entry.Account, "old", fmt.Sprintf("last changed %d days ago", int(age.Hours()/24)))
+		}
+	}
+
+	for _, entry := range pins.Entries {
+		if reason := weakPINReason(entry.PIN); reason != "" {
+			add(severityHigh, "pin", entry.Name, entry.Account, "guessable", reason)
+		}
+	}
+
+	// MFA entries use Account for the service and Name for the user, the
+	// other way round from passwords and PINs.
+	for _, entry := range mfa.Entries {
+		key, err := decodeSecret(entry.Secret)
+		if err != nil {
+			add(severityHigh, "mfa", entry.Account, entry.Name, "invalid-secret", err.Error())
+			continue
+		}
+		if bits := len(key) * 8; bits < minTOTPSecretBits {
+			add(severityMedium, "mfa", entry.Account, entry.Name, "short-secret", fmt.Sprintf("%d-bit secret, at least %d bits recommended", bits, minTOTPSecretBits))
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severityOrder[report.Findings[i].Severity] < severityOrder[report.Findings[j].Severity]
+	})
+	return report, nil
+}
+
+// parseSeverity checks a --fail-on value.
+func parseSeverity(severity string) (string, error) {
+	if _, ok := severityOrder[severity]; !ok {
+		return "", fmt.Errorf("unknown severity '%s' (use high, medium or low)", severity)
+	}
+	return severity, nil
+}
+
+// countAtLeast returns the number of findings of severity or worse.
+func (r *AuditReport) countAtLeast(severity string) int {
+	n := 0
+	for _, f := range r.Findings {
+		if severityOrder[f.Severity] <= severityOrder[severity] {
+			n++
+		}
+	}
+	return n
+}
+
+// AuditVault prints the audit report as a table, or as JSON if asJSON is
+// set, and returns the number of findings at opts.FailOn severity or worse.
+func AuditVault(opts AuditOptions, asJSON bool) (int, error) {
+	report, err := buildAuditReport(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal audit report: %v", err)
+		}
+		fmt.Println(string(data))
+		return report.countAtLeast(opts.FailOn), nil
+	}
+
+	fmt.Println("Security Audit:")
+	fmt.Println("===============")
+	fmt.Printf("Checked %d password(s), %d PIN(s), %d MFA secret(s)\n\n", report.Passwords, report.PINs, report.MFA)
+
+	if len(report.Findings) == 0 {
+		fmt.Println("No issues found")
+		printUndated(report.Undated)
+		return 0, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tKIND\tNAME\tACCOUNT\tISSUE\tDETAIL")
+	for _, f := range report.Findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Kind, f.Name, f.Account, f.Issue, f.Detail)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d issue(s) found\n", len(report.Findings))
+	printUndated(report.Undated)
+	return report.countAtLeast(opts.FailOn), nil
+}
+
+func printUndated(undated []string) {
+	if len(undated) == 0 {
+		return
+	}
+	fmt.Printf("\n%d password(s) have no change date and were not checked for age:\n", len(undated))
+	for _, label := range undated {
+		fmt.Printf("  %s\n", label)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Bitwarden item and KDF types as used in its JSON exports.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+	bitwardenSSHKey     = 5
+
+	bitwardenPBKDF2   = 0
+	bitwardenArgon2id = 1
+
+	bitwardenLinkedField = 3
+)
+
+var bitwardenItemKinds = map[int]string{
+	bitwardenSecureNote: "note",
+	bitwardenCard:       "card",
+	bitwardenIdentity:   "identity",
+	bitwardenSSHKey:     "ssh-key",
+}
+
+type bitwardenExport struct {
+	Encrypted         bool            `json:"encrypted"`
+	PasswordProtected bool            `json:"passwordProtected"`
+	Salt              string          `json:"salt"`
+	KdfType           int             `json:"kdfType"`
+	KdfIterations     int             `json:"kdfIterations"`
+	KdfMemory         int             `json:"kdfMemory"` // MiB, Argon2id only
+	KdfParallelism    int             `json:"kdfParallelism"`
+	EncKeyValidation  string          `json:"encKeyValidation_DO_NOT_EDIT"`
+	Data              string          `json:"data"`
+	Items             []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type         int    `json:"type"`
+	Name         string `json:"name"`
+	Notes        string `json:"notes"`
+	RevisionDate string `json:"revisionDate"`
+	Fields       []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login *struct {
+		Username             string `json:"username"`
+		Password             string `json:"password"`
+		Totp                 string `json:"totp"`
+		PasswordRevisionDate string `json:"passwordRevisionDate"`
+		URIs                 []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+}
+
+// bitwardenExportKeys derives the encryption and MAC keys of a
+// password-protected export: PBKDF2-SHA256 or Argon2id over the export
+// password, stretched with HKDF-Expand like every Bitwarden key.
+func bitwardenExportKeys(password string, export *bitwardenExport) (encKey, macKey []byte, err error) {
+	if export.KdfIterations <= 0 {
+		return nil, nil, fmt.Errorf("invalid KDF iterations %d", export.KdfIterations)
+	}
+
+	var key []byte
+	switch export.KdfType {
+	case bitwardenPBKDF2:
+		key, err = pbkdf2.Key(sha256.New, password, []byte(export.Salt), export.KdfIterations, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive export key: %v", err)
+		}
+	case bitwardenArgon2id:
+		if export.KdfMemory <= 0 || export.KdfMemory > 1024 || export.KdfParallelism <= 0 || export.KdfParallelism > 16 {
+			return nil, nil, fmt.Errorf("unsupported Argon2id parameters (%d MiB, parallelism %d)", export.KdfMemory, export.KdfParallelism)
+		}
+		salt := sha256.Sum256([]byte(export.Salt))
+		key = argon2.IDKey([]byte(password), salt[:], uint32(export.KdfIterations), uint32(export.KdfMemory)*1024, uint8(export.KdfParallelism), 32)
+	default:
+		return nil, nil, fmt.Errorf("unknown KDF type %d", export.KdfType)
+	}
+
+	if encKey, err = hkdf.Expand(sha256.New, key, "enc", 32); err != nil {
+		return nil, nil, err
+	}
+	if macKey, err = hkdf.Expand(sha256.New, key, "mac", 32); err != nil {
+		return nil, nil, err
+	}
+	return encKey, macKey, nil
+}
+
+// decryptBitwardenString decrypts a type 2 cipher string,
+// "2.<iv>|<ciphertext>|<mac>" in base64: AES-256-CBC with PKCS#7 padding
+// and an HMAC-SHA256 over iv and ciphertext.
+func decryptBitwardenString(encrypted string, encKey, macKey []byte) ([]byte, error) {
+	body, ok := strings.CutPrefix(encrypted, "2.")
+	if !ok {
+		return nil, fmt.Errorf("unsupported cipher string type")
+	}
+	parts := strings.Split(body, "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed cipher string")
+	}
+
+	var decoded [3][]byte
+	for i, part := range parts {
+		b, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("malformed cipher string: %v", err)
+		}
+		decoded[i] = b
+	}
+	iv, ciphertext, tag := decoded[0], decoded[1], decoded[2]
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, fmt.Errorf("wrong password or corrupted export")
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("malformed cipher string")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// readBitwardenExport parses a JSON export, asking for the export password
+// if it is password protected.
+func readBitwardenExport(path string) (*bitwardenExport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %v", err)
+	}
+
+	export := &bitwardenExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return nil, fmt.Errorf("failed to parse export: %v", err)
+	}
+	if !export.Encrypted {
+		return export, nil
+	}
+	if !export.PasswordProtected {
+		return nil, fmt.Errorf("account-restricted encrypted exports can only be read by Bitwarden; export as password protected or unencrypted JSON")
+	}
+
+	password, err := promptSecret("Export password: ")
+	if err != nil {
+		return nil, err
+	}
+	return decryptBitwardenExport(export, password)
+}
+
+// decryptBitwardenExport unlocks a password-protected export and returns
+// the unencrypted export held in its data field.
+func decryptBitwardenExport(export *bitwardenExport, password string) (*bitwardenExport, error) {
+	encKey, macKey, err := bitwardenExportKeys(password, export)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decryptBitwardenString(export.EncKeyValidation, encKey, macKey); err != nil {
+		return nil, fmt.Errorf("failed to unlock export: %v", err)
+	}
+	plaintext, err := decryptBitwardenString(export.Data, encKey, macKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt export: %v", err)
+	}
+
+	decrypted := &bitwardenExport{}
+	if err := json.Unmarshal(plaintext, decrypted); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted export: %v", err)
+	}
+	return decrypted, nil
+}
+
+// bitwardenNotes returns the item's notes with its custom fields appended
+// as "name: value" lines.
+func bitwardenNotes(item bitwardenItem) string {
+	lines := []string{}
+	if item.Notes != "" {
+		lines = append(lines, item.Notes)
+	}
+	for _, field := range item.Fields {
+		if field.Type != bitwardenLinkedField {
+			lines = append(lines, fmt.Sprintf("%s: %s", field.Name, field.Value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func bitwardenBatch(export *bitwardenExport) *importBatch {
+	batch := &importBatch{Source: "Bitwarden"}
+
+	for _, item := range export.Items {
+		if item.Type != bitwardenLogin || item.Login == nil {
+			kind, ok := bitwardenItemKinds[item.Type]
+			if !ok {
+				kind = fmt.Sprintf("type %d", item.Type)
+			}
+			batch.skip(kind, item.Name, "", "not a login item")
+			continue
+		}
+		login := item.Login
+
+		entry := PasswordEntry{
+			Name:     item.Name,
+			Account:  login.Username,
+			Password: login.Password,
+			Notes:    bitwardenNotes(item),
+		}
+		for _, uri := range login.URIs {
+			if uri.URI != "" {
+				entry.URIs = append(entry.URIs, uri.URI)
+			}
+		}
+		for _, date := range []string{login.PasswordRevisionDate, item.RevisionDate} {
+			if t, err := time.Parse(time.RFC3339, date); err == nil {
+				entry.UpdatedAt = &t
+				break
+			}
+		}
+		batch.addLogin(entry, login.Totp)
+	}
+
+	return batch
+}
+
+// ImportBitwarden imports the login items of a Bitwarden JSON export
+// (plain or password protected) as password and MFA entries.
+func ImportBitwarden(path string, dryRun bool) error {
+	export, err := readBitwardenExport(path)
+	if err != nil {
+		return err
+	}
+	return applyImport(bitwardenBatch(export), dryRun)
+}
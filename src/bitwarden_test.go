@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fixtures are password-protected exports written by
+// testdata/gen_bitwarden.js with the password "correct horse".
+func TestDecryptBitwardenExport(t *testing.T) {
+	for _, name := range []string{"bitwarden_pbkdf2.json", "bitwarden_argon2id.json"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			export := &bitwardenExport{}
+			if err := json.Unmarshal(data, export); err != nil {
+				t.Fatal(err)
+			}
+			if !export.PasswordProtected {
+				t.Fatal("fixture is not password protected")
+			}
+
+			if _, err := decryptBitwardenExport(export, "wrong horse"); err == nil {
+				t.Error("wrong password: expected an error")
+			}
+
+			decrypted, err := decryptBitwardenExport(export, "correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch := bitwardenBatch(decrypted)
+
+			if len(batch.Passwords) != 1 {
+				t.Fatalf("got %d password(s), want 1", len(batch.Passwords))
+			}
+			got := batch.Passwords[0]
+			if got.Name != "example.com" || got.Account != "alice" || got.Password != "Tr0ub4dor&3" {
+				t.Errorf("got %s (%s) %q", got.Name, got.Account, got.Password)
+			}
+			if want := []string{"https://example.com/login", "https://example.org"}; !reflect.DeepEqual(got.URIs, want) {
+				t.Errorf("URIs = %q, want %q", got.URIs, want)
+			}
+			if want := "first line\nsecond line\nPIN hint: birthday\nRecovery: r3c0very"; got.Notes != want {
+				t.Errorf("Notes = %q, want %q", got.Notes, want)
+			}
+			if want := time.Date(2024, 3, 5, 10, 20, 30, 0, time.UTC); got.UpdatedAt == nil || !got.UpdatedAt.Equal(want) {
+				t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt, want)
+			}
+
+			wantMFA := []MFAEntry{
+				{Account: "example.com", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: 30},
+				{Account: "totp only", Name: "bob", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Period: 30},
+			}
+			if !reflect.DeepEqual(batch.MFA, wantMFA) {
+				t.Errorf("MFA = %+v, want %+v", batch.MFA, wantMFA)
+			}
+
+			wantSkipped := []importResult{{importSkip, "note", "wifi", "", "not a login item"}}
+			if !reflect.DeepEqual(batch.Skipped, wantSkipped) {
+				t.Errorf("Skipped = %+v, want %+v", batch.Skipped, wantSkipped)
+			}
+		})
+	}
+}
+
+func TestDecryptBitwardenExportTampered(t *testing.T) {
+	data, err := os.ReadFile("testdata/bitwarden_pbkdf2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	export := &bitwardenExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		t.Fatal(err)
+	}
+
+	// Swap two characters of the ciphertext; the MAC must catch it.
+	parts := strings.Split(export.Data, "|")
+	ct := []byte(parts[1])
+	ct[0], ct[1] = ct[1], ct[0]
+	if ct[0] == ct[1] {
+		ct[0] ^= 1
+	}
+	parts[1] = string(ct)
+	export.Data = strings.Join(parts, "|")
+
+	if _, err := decryptBitwardenExport(export, "correct horse"); err == nil {
+		t.Error("tampered data: expected an error")
+	}
+}
+
+func TestBitwardenBatch(t *testing.T) {
+	const export = `{"encrypted": false, "items": [
+		{"type": 1, "name": "mail", "revisionDate": "2023-01-02T03:04:05Z",
+		 "login": {"username": "carol", "password": "pw1", "uris": [{"uri": ""}, {"uri": "https://mail.example"}]}},
+		{"type": 1, "name": "", "login": {"username": "dave", "password": "pw2"}},
+		{"type": 1, "name": "empty", "login": {"username": "erin"}},
+		{"type": 1, "name": "anonymous", "login": {"password": "pw3"}},
+		{"type": 1, "name": "bad totp", "login": {"username": "frank", "password": "pw4", "totp": "not base32!"}},
+		{"type": 1, "name": "steam", "login": {"username": "gina", "totp": "steam://JBSWY3DPEHPK3PXP"}},
+		{"type": 1, "name": "no login"},
+		{"type": 3, "name": "visa"},
+		{"type": 4, "name": "me"},
+		{"type": 9, "name": "future"}
+	]}`
+
+	var parsed bitwardenExport
+	if err := json.Unmarshal([]byte(export), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	batch := bitwardenBatch(&parsed)
+
+	var passwords []string
+	for _, entry := range batch.Passwords {
+		passwords = append(passwords, entry.Name+"/"+entry.Account)
+	}
+	if want := []string{"mail/carol", "bad totp/frank"}; !reflect.DeepEqual(passwords, want) {
+		t.Errorf("passwords = %q, want %q", passwords, want)
+	}
+
+	mail := batch.Passwords[0]
+	if want := []string{"https://mail.example"}; !reflect.DeepEqual(mail.URIs, want) {
+		t.Errorf("URIs = %q, want %q", mail.URIs, want)
+	}
+	if want := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC); mail.UpdatedAt == nil || !mail.UpdatedAt.Equal(want) {
+		t.Errorf("UpdatedAt = %v, want the item revision date %v", mail.UpdatedAt, want)
+	}
+	if mail.Source != "bitwarden" || mail.Config != "imported from Bitwarden" || mail.Length != 3 {
+		t.Errorf("got source %q, config %q, length %d", mail.Source, mail.Config, mail.Length)
+	}
+	if batch.Passwords[1].UpdatedAt != nil {
+		t.Errorf("undated item got UpdatedAt %v", batch.Passwords[1].UpdatedAt)
+	}
+
+	if len(batch.MFA) != 1 || batch.MFA[0].Type != mfaTypeSteam || batch.MFA[0].Name != "gina" {
+		t.Errorf("MFA = %+v, want one Steam Guard entry for gina", batch.MFA)
+	}
+
+	reasons := map[string]string{}
+	for _, skipped := range batch.Skipped {
+		reasons[skipped.Kind+" "+skipped.Name] = skipped.Detail
+	}
+	want := map[string]string{
+		"login ":             "no name",
+		"login empty":        "no password or TOTP",
+		"password anonymous": "no username",
+		"type 1 no login":    "not a login item",
+		"card visa":          "not a login item",
+		"identity me":        "not a login item",
+		"type 9 future":      "not a login item",
+	}
+	for key, reason := range want {
+		if reasons[key] != reason {
+			t.Errorf("skip %q: got %q, want %q", key, reasons[key], reason)
+		}
+	}
+	if reasons["mfa bad totp"] == "" {
+		t.Error("bad TOTP secret was not skipped")
+	}
+	if len(batch.Skipped) != len(want)+1 {
+		t.Errorf("got %d skipped item(s), want %d: %+v", len(batch.Skipped), len(want)+1, batch.Skipped)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The Have I Been Pwned "ordered by hash" dump has one "SHA1HEX:COUNT" line
+// per password, sorted by hash. Files are tens of GB, so lookups binary
+// search on byte offsets instead of reading the file.
+const (
+	hibpHashLength = 40
+	hibpScanWindow = 64 * 1024 // Below this the remaining range is scanned linearly
+	hibpMaxLine    = 128
+)
+
+type hibpFile struct {
+	file *os.File
+	size int64
+}
+
+func openHIBPFile(path string) (*hibpFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hash file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat hash file: %v", err)
+	}
+	return &hibpFile{file: file, size: info.Size()}, nil
+}
+
+func (h *hibpFile) Close() error {
+	return h.file.Close()
+}
+
+// lineAt returns the first line starting at or after offset, its start
+// offset and the offset of the line that follows it. start is h.size if
+// there is no such line.
+func (h *hibpFile) lineAt(offset int64) (line []byte, start, next int64, err error) {
+	// Start one byte early so a line beginning exactly at offset is kept.
+	start = max(offset-1, 0)
+	r := bufio.NewReaderSize(io.NewSectionReader(h.file, start, h.size-start), 2*hibpMaxLine)
+
+	if offset > 0 {
+		skipped, err := r.ReadSlice('\n')
+		start += int64(len(skipped))
+		if err == io.EOF {
+			return nil, h.size, h.size, nil
+		}
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("unexpected data at offset %d; is this a HIBP hash file?", offset)
+		}
+	}
+
+	line, err = r.ReadSlice('\n')
+	next = start + int64(len(line))
+	if err == io.EOF {
+		if len(line) == 0 {
+			return nil, h.size, h.size, nil
+		}
+	} else if err != nil {
+		return nil, 0, 0, fmt.Errorf("unexpected data at offset %d; is this a HIBP hash file?", start)
+	}
+	return bytes.TrimSpace(line), start, next, nil
+}
+
+// parseHIBPLine splits a "HASH:COUNT" line. The hash is upper-cased.
+func parseHIBPLine(line []byte) (string, int, error) {
+	hash, countText, ok := strings.Cut(string(line), ":")
+	if !ok || len(hash) != hibpHashLength {
+		return "", 0, fmt.Errorf("unexpected line '%s'; is this a HIBP SHA-1 hash file?", line)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countText))
+	if err != nil {
+		return "", 0, fmt.Errorf("unexpected count in line '%s'", line)
+	}
+	return strings.ToUpper(hash), count, nil
+}
+
+// lookup returns how often the password with the given SHA-1 hash (upper
+// case hex) appears in the dump, or 0 if it does not.
+func (h *hibpFile) lookup(hash string) (int, error) {
+	// Invariant: a line for hash, if present, starts in [lo, hi). lo and hi
+	// are always line starts (or the end of the file).
+	lo, hi := int64(0), h.size
+	for hi-lo > hibpScanWindow {
+		mid := lo + (hi-lo)/2
+		line, start, next, err := h.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		lineHash, count, err := parseHIBPLine(line)
+		if err != nil {
+			return 0, err
+		}
+		switch strings.Compare(lineHash, hash) {
+		case 0:
+			return count, nil
+		case -1:
+			lo = next
+		default:
+			hi = start
+		}
+	}
+
+	scanner := bufio.NewScanner(io.NewSectionReader(h.file, lo, hi-lo))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lineHash, count, err := parseHIBPLine(line)
+		if err != nil {
+			return 0, err
+		}
+		if lineHash == hash {
+			return count, nil
+		}
+		if lineHash > hash {
+			break
+		}
+	}
+	return 0, scanner.Err()
+}
+
+func passwordSHA1(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// CheckBreaches looks up every stored password (or only name/account if
+// given) in a local HIBP hash dump and prints how often each was seen.
+// Passwords are never printed. It returns the number of breached entries.
+func CheckBreaches(path, name, account string) (int, error) {
+	hibp, err := openHIBPFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer hibp.Close()
+
+	storage, err := loadPasswordStorage()
+	if err != nil {
+		return 0, err
+	}
+
+	checked, breached := 0, 0
+	fmt.Println("Breach Check:")
+	fmt.Println("=============")
+	for _, entry := range storage.Entries {
+		if name != "" && (entry.Name != name || entry.Account != account) {
+			continue
+		}
+		checked++
+
+		count, err := hibp.lookup(passwordSHA1(entry.Password))
+		if err != nil {
+			return breached, fmt.Errorf("lookup failed for %s (%s): %v", entry.Name, entry.Account, err)
+		}
+		if count > 0 {
+			breached++
+			fmt.Printf("  BREACHED %s (%s): seen %d times\n", entry.Name, entry.Account, count)
+		} else {
+			fmt.Printf("  ok       %s (%s)\n", entry.Name, entry.Account)
+		}
+
+		if entry.PendingPassword != "" {
+			count, err := hibp.lookup(passwordSHA1(entry.PendingPassword))
+			if err != nil {
+				return breached, fmt.Errorf("lookup failed for %s (%s): %v", entry.Name, entry.Account, err)
+			}
+			if count > 0 {
+				breached++
+				fmt.Printf("  BREACHED %s (%s) pending password: seen %d times\n", entry.Name, entry.Account, count)
+			}
+		}
+	}
+
+	if name != "" && checked == 0 {
+		return 0, fmt.Errorf("password not found for %s (%s)", name, account)
+	}
+
+	fmt.Printf("\n%d password(s) checked, %d found in breaches\n", checked, breached)
+	return breached, nil
+}
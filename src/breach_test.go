@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeHIBPFile(t *testing.T, hashes []string, eol string, trailingNewline bool) *hibpFile {
+	t.Helper()
+	var b strings.Builder
+	for i, hash := range hashes {
+		fmt.Fprintf(&b, "%s:%d", hash, i+1)
+		if i < len(hashes)-1 || trailingNewline {
+			b.WriteString(eol)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hibp, err := openHIBPFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { hibp.Close() })
+	return hibp
+}
+
+func TestHIBPLookup(t *testing.T) {
+	// Odd indexes are left out of the file so absent hashes fall between
+	// present ones.
+	var all []string
+	for i := 0; i < 12000; i++ {
+		all = append(all, passwordSHA1(fmt.Sprintf("password%d", i)))
+	}
+	sort.Strings(all)
+	var present, absent []string
+	for i, hash := range all {
+		if i%2 == 0 {
+			present = append(present, hash)
+		} else {
+			absent = append(absent, hash)
+		}
+	}
+
+	for _, size := range []int{1, 3, len(present)} {
+		for _, eol := range []string{"\n", "\r\n"} {
+			for _, trailingNewline := range []bool{true, false} {
+				name := fmt.Sprintf("%d lines, %q, trailing newline %v", size, eol, trailingNewline)
+				t.Run(name, func(t *testing.T) {
+					hashes := present[:size]
+					hibp := writeHIBPFile(t, hashes, eol, trailingNewline)
+					if size > 1000 && hibp.size <= hibpScanWindow {
+						t.Fatalf("file of %d bytes does not exercise the binary search", hibp.size)
+					}
+
+					want := map[string]int{
+						hashes[0]:          1,    // First line
+						hashes[size-1]:     size, // Last line
+						hashes[size/2]:     size/2 + 1,
+						hashes[(size-1)/3]: (size-1)/3 + 1,
+					}
+					for hash, count := range want {
+						got, err := hibp.lookup(hash)
+						if err != nil {
+							t.Fatalf("lookup(%s): %v", hash, err)
+						}
+						if got != count {
+							t.Errorf("lookup(%s) = %d, want %d", hash, got, count)
+						}
+					}
+
+					missing := []string{
+						strings.Repeat("0", hibpHashLength), // Before the first line
+						strings.Repeat("F", hibpHashLength), // After the last line
+						absent[0],
+						absent[size/2],
+						absent[size-1],
+					}
+					for _, hash := range missing {
+						got, err := hibp.lookup(hash)
+						if err != nil {
+							t.Fatalf("lookup(%s): %v", hash, err)
+						}
+						if got != 0 {
+							t.Errorf("lookup(%s) = %d for an absent hash", hash, got)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestHIBPLookupEveryLine(t *testing.T) {
+	var hashes []string
+	for i := 0; i < 3000; i++ {
+		hashes = append(hashes, passwordSHA1(fmt.Sprintf("secret%d", i)))
+	}
+	sort.Strings(hashes)
+	hibp := writeHIBPFile(t, hashes, "\r\n", false)
+	if hibp.size <= hibpScanWindow {
+		t.Fatalf("file of %d bytes does not exercise the binary search", hibp.size)
+	}
+
+	for i, hash := range hashes {
+		got, err := hibp.lookup(hash)
+		if err != nil {
+			t.Fatalf("lookup(%s): %v", hash, err)
+		}
+		if got != i+1 {
+			t.Fatalf("lookup(%s) = %d, want %d", hash, got, i+1)
+		}
+	}
+}
+
+func TestHIBPLookupRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ntlm.txt")
+	if err := os.WriteFile(path, []byte("8846F7EAEE8FB117AD06BDD830B7586C:1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hibp, err := openHIBPFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hibp.Close()
+	if _, err := hibp.lookup(passwordSHA1("password")); err == nil {
+		t.Error("NTLM hash file accepted")
+	}
+}
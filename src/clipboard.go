@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands lists the helpers tried, in order, to put text on the
+// system clipboard. The first one found on PATH wins.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	default:
+		return [][]string{
+			{"wl-copy"},
+			{"xclip", "-selection", "clipboard"},
+			{"xsel", "--clipboard", "--input"},
+			{"clip.exe"},
+		}
+	}
+}
+
+func copyToClipboard(text string) error {
+	for _, args := range clipboardCommands() {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+
+		cmd := exec.Command(path, args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to run %s: %v", args[0], err)
+		}
+		return nil
+	}
+
+	// No helper available (e.g. over SSH): fall back to the OSC 52 escape
+	// sequence, which most modern terminals forward to the local clipboard.
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	if _, err := fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\x07", encoded); err != nil {
+		return fmt.Errorf("failed to write clipboard escape sequence: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvFields are the entry fields a CSV column can be mapped to.
+var csvFields = []string{"name", "account", "password", "url", "notes", "totp", "group", "updated", "archived"}
+
+// csvFormat describes a password manager's CSV export: the header names,
+// matched case-insensitively, that hold each entry field.
+type csvFormat struct {
+	Source  string
+	Columns map[string][]string
+}
+
+var csvFormats = map[string]csvFormat{
+	"chrome": {"Chrome", map[string][]string{
+		"name": {"name"}, "url": {"url"}, "account": {"username"}, "password": {"password"}, "notes": {"note"},
+	}},
+	"firefox": {"Firefox", map[string][]string{
+		"url": {"url"}, "account": {"username"}, "password": {"password"}, "updated": {"timePasswordChanged"},
+	}},
+	"lastpass": {"LastPass", map[string][]string{
+		"name": {"name"}, "url": {"url"}, "account": {"username"}, "password": {"password"},
+		"totp": {"totp"}, "notes": {"extra"}, "group": {"grouping"},
+	}},
+	"1password": {"1Password", map[string][]string{
+		"name": {"title"}, "url": {"url", "website"}, "account": {"username"}, "password": {"password"},
+		"totp": {"otpauth", "one-time password"}, "notes": {"notes"}, "archived": {"archived"},
+	}},
+	"generic": {"CSV", map[string][]string{
+		"name": {"name", "title"}, "url": {"url", "uri", "website"}, "account": {"account", "username", "login", "user", "email"},
+		"password": {"password"}, "notes": {"notes", "note"}, "totp": {"totp", "otp"}, "group": {"group", "folder"},
+	}},
+}
+
+// lastPassSecureNote is the URL LastPass gives secure notes in its export.
+const lastPassSecureNote = "http://sn"
+
+func csvFormatNames() []string {
+	var names []string
+	for name := range csvFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseCSVMapping parses a generic column mapping such as
+// "name=Title,account=Login,password=Pass" into field -> header name.
+func parseCSVMapping(mapping string) (map[string][]string, error) {
+	columns := make(map[string][]string)
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid column mapping '%s' (use field=Column)", pair)
+		}
+		valid := false
+		for _, f := range csvFields {
+			valid = valid || f == field
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown field '%s' in column mapping (use %s)", field, strings.Join(csvFields, ", "))
+		}
+		columns[field] = []string{column}
+	}
+	return columns, nil
+}
+
+// remapCSVFormat returns format with the columns of the given fields
+// replaced by the --map ones.
+func remapCSVFormat(format csvFormat, columns map[string][]string) csvFormat {
+	merged := make(map[string][]string)
+	for field, names := range format.Columns {
+		merged[field] = names
+	}
+	for field, names := range columns {
+		merged[field] = names
+	}
+	format.Columns = merged
+	return format
+}
+
+// csvColumnIndexes finds the column of each mapped field in header.
+func csvColumnIndexes(header []string, columns map[string][]string) map[string]int {
+	indexes := make(map[string]int)
+	for field, names := range columns {
+		for _, name := range names {
+			for i, column := range header {
+				if strings.EqualFold(strings.TrimSpace(column), name) {
+					indexes[field] = i
+					break
+				}
+			}
+			if _, ok := indexes[field]; ok {
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// csvHostName names an entry after its site when the export has no name
+// column, as in Firefox exports.
+func csvHostName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// parseCSVTime accepts RFC 3339 times and Unix times in milliseconds.
+func parseCSVTime(value string) (time.Time, bool) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil && ms > 0 {
+		return time.UnixMilli(ms), true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+// csvBatch reads records with format's columns. Every column named in
+// mapped, the --map fields, must be present in the header.
+func csvBatch(records [][]string, format csvFormat, mapped map[string][]string) (*importBatch, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	header := records[0]
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	indexes := csvColumnIndexes(header, format.Columns)
+	var fields []string
+	for field := range mapped {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if _, ok := indexes[field]; !ok {
+			return nil, fmt.Errorf("column '%s' mapped to %s not found in header: %s", mapped[field][0], field, strings.Join(header, ","))
+		}
+	}
+	if _, ok := indexes["password"]; !ok {
+		return nil, fmt.Errorf("no password column found in header: %s", strings.Join(header, ","))
+	}
+	_, hasName := indexes["name"]
+	_, hasURL := indexes["url"]
+	if !hasName && !hasURL {
+		return nil, fmt.Errorf("no name or URL column found in header: %s", strings.Join(header, ","))
+	}
+
+	batch := &importBatch{Source: format.Source}
+	for n, record := range records[1:] {
+		row := n + 2
+		get := func(field string) string {
+			i, ok := indexes[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			if field == "password" {
+				return record[i]
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if get("url") == lastPassSecureNote {
+			batch.skip("note", get("name"), "", fmt.Sprintf("row %d: secure note", row))
+			continue
+		}
+		if strings.EqualFold(get("archived"), "true") {
+			batch.skip("login", get("name"), get("account"), fmt.Sprintf("row %d: archived", row))
+			continue
+		}
+
+		entry := PasswordEntry{
+			Name:     get("name"),
+			Account:  get("account"),
+			Password: get("password"),
+			Notes:    get("notes"),
+			Group:    strings.ReplaceAll(get("group"), `\`, "/"), // LastPass separates folders with backslashes
+		}
+		if u := get("url"); u != "" {
+			entry.URIs = []string{u}
+			if entry.Name == "" {
+				entry.Name = csvHostName(u)
+			}
+		}
+		if t, ok := parseCSVTime(get("updated")); ok {
+			entry.UpdatedAt = &t
+		}
+
+		skipped := len(batch.Skipped)
+		batch.addLogin(entry, get("totp"))
+		for i := skipped; i < len(batch.Skipped); i++ {
+			batch.Skipped[i].Detail = fmt.Sprintf("row %d: %s", row, batch.Skipped[i].Detail)
+		}
+	}
+	return batch, nil
+}
+
+// ImportCSV imports a CSV password export. formatName selects the column
+// layout of a browser or password manager; the generic layout can be
+// remapped with mapping, e.g. "name=Title,account=Login".
+func ImportCSV(path, formatName, mapping string, dryRun bool) error {
+	format, ok := csvFormats[formatName]
+	if !ok {
+		return fmt.Errorf("unknown CSV format '%s' (use %s)", formatName, strings.Join(csvFormatNames(), ", "))
+	}
+	var columns map[string][]string
+	if mapping != "" {
+		if formatName != "generic" {
+			return fmt.Errorf("--map can only be used with --format generic")
+		}
+		var err error
+		columns, err = parseCSVMapping(mapping)
+		if err != nil {
+			return err
+		}
+		format = remapCSVFormat(format, columns)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read export: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to parse CSV: %v", err)
+	}
+
+	batch, err := csvBatch(records, format, columns)
+	if err != nil {
+		return err
+	}
+	return applyImport(batch, dryRun)
+}
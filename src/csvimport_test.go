@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTestCSV(t *testing.T, text string) [][]string {
+	t.Helper()
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestCSVBatch(t *testing.T) {
+	changed := time.UnixMilli(1709634030123)
+
+	tests := []struct {
+		format    string
+		mapping   string
+		csv       string
+		passwords []PasswordEntry
+		mfa       []string
+		skipped   []string
+	}{
+		{
+			format: "chrome",
+			csv: "\ufeffname,url,username,password,note\n" +
+				"example.com,https://example.com/login,alice,pw1,hello\n",
+			passwords: []PasswordEntry{
+				{Name: "example.com", Account: "alice", Password: "pw1", Notes: "hello", URIs: []string{"https://example.com/login"}},
+			},
+		},
+		{
+			format: "firefox",
+			csv: `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"` + "\n" +
+				`"https://www.example.com","bob"," spaced ",,"https://www.example.com","{1}","1","1","1709634030123"` + "\n" +
+				`"https://example.org","carol","pw",,"","{2}","1","1",""` + "\n",
+			passwords: []PasswordEntry{
+				{Name: "example.com", Account: "bob", Password: " spaced ", URIs: []string{"https://www.example.com"}, UpdatedAt: &changed},
+				{Name: "example.org", Account: "carol", Password: "pw", URIs: []string{"https://example.org"}},
+			},
+		},
+		{
+			format: "lastpass",
+			csv: "url,username,password,totp,extra,name,grouping,fav\n" +
+				"https://mail.example,dave,pw,JBSWY3DPEHPK3PXP,,mail,Work\\Email,0\n" +
+				"http://sn,,,,secret note body,wifi,,0\n",
+			passwords: []PasswordEntry{
+				{Name: "mail", Account: "dave", Password: "pw", Group: "Work/Email", URIs: []string{"https://mail.example"}},
+			},
+			mfa:     []string{"mail/dave"},
+			skipped: []string{"note wifi: row 3: secure note"},
+		},
+		{
+			format: "1password",
+			csv: "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+				"bank,https://bank.example,erin,pw,otpauth://totp/bank:erin?secret=JBSWY3DPEHPK3PXP,false,false,,\n" +
+				"old,https://old.example,erin,pw,,false,true,,\n",
+			passwords: []PasswordEntry{
+				{Name: "bank", Account: "erin", Password: "pw", URIs: []string{"https://bank.example"}},
+			},
+			mfa:     []string{"bank/erin"},
+			skipped: []string{"login old: row 3: archived"},
+		},
+		{
+			format: "generic",
+			csv: "Title,Email,Password,Folder\n" +
+				"forum,frank@example.com,pw,Social\n" +
+				"nameless,,pw,\n" +
+				",gina,pw,\n",
+			passwords: []PasswordEntry{
+				{Name: "forum", Account: "frank@example.com", Password: "pw", Group: "Social"},
+			},
+			skipped: []string{"password nameless: row 3: no username", "login : row 4: no name"},
+		},
+		{
+			format:  "generic",
+			mapping: "name=Site,account=Login,password=Secret",
+			csv: "Site,Login,Secret,Username\n" +
+				"vpn,henry,pw,ignored\n",
+			passwords: []PasswordEntry{
+				{Name: "vpn", Account: "henry", Password: "pw"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.mapping, func(t *testing.T) {
+			format := csvFormats[tt.format]
+			var columns map[string][]string
+			if tt.mapping != "" {
+				var err error
+				if columns, err = parseCSVMapping(tt.mapping); err != nil {
+					t.Fatal(err)
+				}
+				format = remapCSVFormat(format, columns)
+			}
+
+			batch, err := csvBatch(readTestCSV(t, tt.csv), format, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range tt.passwords {
+				want := &tt.passwords[i]
+				want.Length = characterCount(want.Password)
+				want.Source = strings.ToLower(format.Source)
+				want.Config = "imported from " + format.Source
+			}
+			if !reflect.DeepEqual(batch.Passwords, tt.passwords) {
+				t.Errorf("passwords:\n got %+v\nwant %+v", batch.Passwords, tt.passwords)
+			}
+
+			var mfa []string
+			for _, entry := range batch.MFA {
+				mfa = append(mfa, entry.Account+"/"+entry.Name)
+			}
+			if !reflect.DeepEqual(mfa, tt.mfa) {
+				t.Errorf("MFA = %q, want %q", mfa, tt.mfa)
+			}
+
+			var skipped []string
+			for _, s := range batch.Skipped {
+				skipped = append(skipped, s.Kind+" "+s.Name+": "+s.Detail)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped = %q, want %q", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestCSVBatchMissingColumn(t *testing.T) {
+	tests := []struct {
+		format  string
+		mapping string
+		csv     string
+		err     string
+	}{
+		{"generic", "name=Site", "Title,Username,Password\nx,y,z\n", "column 'Site' mapped to name not found"},
+		{"chrome", "", "name,url,username\nx,y,z\n", "no password column"},
+		{"generic", "", "Username,Password\ny,z\n", "no name or URL column"},
+		{"generic", "", "", "the file is empty"},
+	}
+	for _, tt := range tests {
+		format := csvFormats[tt.format]
+		var columns map[string][]string
+		if tt.mapping != "" {
+			var err error
+			if columns, err = parseCSVMapping(tt.mapping); err != nil {
+				t.Fatal(err)
+			}
+			format = remapCSVFormat(format, columns)
+		}
+		_, err := csvBatch(readTestCSV(t, tt.csv), format, columns)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s %q: got error %v, want %q", tt.format, tt.csv, err, tt.err)
+		}
+	}
+}
+
+func TestParseCSVMapping(t *testing.T) {
+	tests := []struct {
+		mapping string
+		want    map[string][]string
+		err     bool
+	}{
+		{"name=Title, Account = Login ,", map[string][]string{"name": {"Title"}, "account": {"Login"}}, false},
+		{"", map[string][]string{}, false},
+		{"name", nil, true},
+		{"name=", nil, true},
+		{"colour=Red", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCSVMapping(tt.mapping)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: got error %v, want error %v", tt.mapping, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.mapping, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// Display modes for reading a password aloud or off a screen.
+const (
+	displayPlain   = "plain"
+	displayChunked = "chunked"
+	displayNATO    = "nato"
+	displayBig     = "big"
+
+	displayChunkSize = 4
+	bigCharsPerLine  = 10 // Keeps big output within 70 columns
+)
+
+var displayModes = []string{displayPlain, displayChunked, displayNATO, displayBig}
+
+var natoAlphabet = []string{
+	"alfa", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india",
+	"juliett", "kilo", "lima", "mike", "november", "oscar", "papa", "quebec", "romeo",
+	"sierra", "tango", "uniform", "victor", "whiskey", "xray", "yankee", "zulu",
+}
+
+var digitNames = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"}
+
+var symbolNames = map[string]string{
+	" ": "space", "!": "exclamation mark", "\"": "double quote", "#": "hash",
+	"$": "dollar sign", "%": "percent", "&": "ampersand", "'": "apostrophe",
+	"(": "open parenthesis", ")": "close parenthesis", "*": "asterisk", "+": "plus",
+	",": "comma", "-": "hyphen", ".": "period", "/": "slash", ":": "colon",
+	";": "semicolon", "<": "less than", "=": "equals", ">": "greater than",
+	"?": "question mark", "@": "at sign", "[": "open bracket", "\\": "backslash",
+	"]": "close bracket", "^": "caret", "_": "underscore", "`": "backtick",
+	"{": "open brace", "|": "vertical bar", "}": "close brace", "~": "tilde",
+}
+
+func validateDisplayMode(mode string) error {
+	for _, m := range displayModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown display mode '%s' (use %s)", mode, strings.Join(displayModes, ", "))
+}
+
+// chunkPassword splits password into space-separated groups of four
+// characters.
+func chunkPassword(password string) string {
+	chars := splitCharacters(password)
+	var groups []string
+	for i := 0; i < len(chars); i += displayChunkSize {
+		groups = append(groups, strings.Join(chars[i:min(i+displayChunkSize, len(chars))], ""))
+	}
+	return strings.Join(groups, " ")
+}
+
+// spokenName returns how a character is read out: NATO words with the case
+// spelled out for letters, names for digits and ASCII symbols, and the code
+// point for anything else.
+func spokenName(char string) string {
+	if len(char) == 1 {
+		c := char[0]
+		switch {
+		case c >= 'a' && c <= 'z':
+			return "lowercase " + natoAlphabet[c-'a']
+		case c >= 'A' && c <= 'Z':
+			return "UPPERCASE " + strings.ToUpper(natoAlphabet[c-'A'])
+		case c >= '0' && c <= '9':
+			return "digit " + digitNames[c-'0']
+		}
+		if name, ok := symbolNames[char]; ok {
+			return name
+		}
+	}
+
+	var points []string
+	for _, r := range char {
+		points = append(points, fmt.Sprintf("%U", r))
+	}
+	return strings.Join(points, " ")
+}
+
+// natoLines spells password one character per line.
+func natoLines(password string) []string {
+	chars := splitCharacters(password)
+	lines := make([]string, len(chars))
+	for i, char := range chars {
+		lines[i] = fmt.Sprintf("%2d. %s  %s", i+1, char, spokenName(char))
+	}
+	return lines
+}
+
+// glyphIndex returns the position of r's glyph in the 7x13 font mask.
+// Characters the font lacks map to U+FFFD.
+func glyphIndex(r rune) int {
+	for _, rng := range basicfont.Face7x13.Ranges {
+		if r >= rng.Low && r < rng.High {
+			return int(r-rng.Low) + rng.Offset
+		}
+	}
+	return glyphIndex('\ufffd')
+}
+
+// bigLines renders password with the X11 7x13 bitmap font, ten characters
+// per block. Rows left blank across a whole block are trimmed.
+func bigLines(password string) []string {
+	face := basicfont.Face7x13
+	height := face.Ascent + face.Descent
+
+	runes := []rune(password)
+	var lines []string
+	for start := 0; start < len(runes); start += bigCharsPerLine {
+		block := runes[start:min(start+bigCharsPerLine, len(runes))]
+
+		var rows []string
+		for y := 0; y < height; y++ {
+			var row strings.Builder
+			for _, r := range block {
+				top := glyphIndex(r) * height
+				for x := 0; x < face.Advance; x++ {
+					_, _, _, alpha := face.Mask.At(x, top+y).RGBA()
+					if x < face.Width && alpha > 0 {
+						row.WriteByte('#')
+					} else {
+						row.WriteByte(' ')
+					}
+				}
+			}
+			rows = append(rows, strings.TrimRight(row.String(), " "))
+		}
+		for len(rows) > 0 && rows[0] == "" {
+			rows = rows[1:]
+		}
+		for len(rows) > 0 && rows[len(rows)-1] == "" {
+			rows = rows[:len(rows)-1]
+		}
+
+		if start > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, rows...)
+	}
+	return lines
+}
+
+// formatPassword returns password rendered in the given display mode, one
+// string per output line.
+func formatPassword(password, mode string) []string {
+	switch mode {
+	case displayChunked:
+		return []string{chunkPassword(password)}
+	case displayNATO:
+		return natoLines(password)
+	case displayBig:
+		return bigLines(password)
+	default:
+		return []string{password}
+	}
+}
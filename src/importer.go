@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cazzano/password_manager_cli/otp"
+)
+
+const (
+	importCreate   = "create"
+	importConflict = "conflict"
+	importSkip     = "skip"
+)
+
+// importResult is one line of the import report. Secrets are never
+// included.
+type importResult struct {
+	Action  string
+	Kind    string
+	Name    string
+	Account string
+	Detail  string
+}
+
+// importBatch holds the entries read from another password manager's
+// export before they are merged into the stores by applyImport.
+type importBatch struct {
+	Source    string // Display name of the format, e.g. Bitwarden
+	Passwords []PasswordEntry
+	MFA       []MFAEntry
+	PINs      []MPINEntry
+	Skipped   []importResult
+}
+
+func (b *importBatch) skip(kind, name, account, reason string) {
+	b.Skipped = append(b.Skipped, importResult{importSkip, kind, name, account, reason})
+}
+
+// addPassword queues an imported password. Imported entries record their
+// source instead of generator settings; UpdatedAt stays nil when the source
+// has no date, so the password isn't mistaken for a fresh one.
+func (b *importBatch) addPassword(entry PasswordEntry) {
+	entry.Length = characterCount(entry.Password)
+	entry.Source = strings.ToLower(b.Source)
+	entry.Config = "imported from " + b.Source
+	b.Passwords = append(b.Passwords, entry)
+}
+
+// addLogin queues a login item: its password, if any, and its TOTP field,
+// if any, as an MFA entry for the same service and user.
+func (b *importBatch) addLogin(entry PasswordEntry, totp string) {
+	switch {
+	case entry.Name == "":
+		b.skip("login", "", entry.Account, "no name")
+		return
+	case entry.Password == "" && totp == "":
+		b.skip("login", entry.Name, entry.Account, "no password or TOTP")
+		return
+	}
+
+	if totp != "" {
+		b.addTOTP(entry.Name, entry.Account, totp)
+	}
+	if entry.Password == "" {
+		return
+	}
+	if entry.Account == "" {
+		b.skip("password", entry.Name, "", "no username")
+		return
+	}
+	b.addPassword(entry)
+}
+
+// addTOTP queues an MFA entry for service/user from a TOTP field, which
+// may hold an otpauth:// URI, a steam:// secret or a bare base32 secret.
+func (b *importBatch) addTOTP(service, user, totp string) {
+	entry, err := mfaEntryFromTOTP(service, user, totp)
+	if err != nil {
+		b.skip("mfa", service, user, err.Error())
+		return
+	}
+	b.MFA = append(b.MFA, entry)
+}
+
+func mfaEntryFromTOTP(service, user, totp string) (MFAEntry, error) {
+	totp = strings.TrimSpace(totp)
+	entry := MFAEntry{Account: service, Name: user, Period: 30}
+
+	switch {
+	case strings.HasPrefix(totp, "steam://"):
+		secret, err := normalizeSteamSecret(strings.TrimPrefix(totp, "steam://"))
+		if err != nil {
+			return MFAEntry{}, err
+		}
+		entry.Secret, entry.Type, entry.Period = secret, mfaTypeSteam, steamGuardPeriod
+	case strings.HasPrefix(totp, "otpauth://"):
+		key, err := otp.ParseKeyURI(totp)
+		if err != nil {
+			return MFAEntry{}, err
+		}
+		if entry.Name == "" {
+			entry.Name = key.AccountName
+		}
+		if key.Encoder == mfaTypeSteam && key.Type == "totp" {
+			entry.Secret, entry.Type, entry.Period = key.Secret, mfaTypeSteam, steamGuardPeriod
+			break
+		}
+		if key.Type != "totp" || key.Algorithm != "SHA1" || key.Digits != 6 {
+			return MFAEntry{}, fmt.Errorf("unsupported OTP settings (%s, %s, %d digits); only 6-digit SHA1 TOTP is supported", key.Type, key.Algorithm, key.Digits)
+		}
+		entry.Secret, entry.Period = key.Secret, key.Period
+	default:
+		entry.Secret = strings.ToUpper(strings.ReplaceAll(totp, " ", ""))
+	}
+
+	if entry.Name == "" {
+		return MFAEntry{}, fmt.Errorf("no username for the MFA entry")
+	}
+	key, err := decodeSecret(entry.Secret)
+	if err != nil {
+		return MFAEntry{}, err
+	}
+	if len(key) == 0 {
+		return MFAEntry{}, fmt.Errorf("empty TOTP secret")
+	}
+	return entry, nil
+}
+
+// mfaEntryURI is the inverse of mfaEntryFromTOTP: the otpauth URI for
+// entry, with the "encoder=steam" parameter KeePassXC uses for Steam Guard.
+func mfaEntryURI(entry MFAEntry) string {
+	secret := strings.TrimRight(cleanSecret(entry.Secret), "=")
+	if entry.Type == mfaTypeSteam {
+		return otp.KeyURI(entry.Account, entry.Name, secret, steamGuardPeriod, steamGuardDigits) + "&encoder=steam"
+	}
+	return otp.KeyURI(entry.Account, entry.Name, secret, entry.Period, 6)
+}
+
+// applyImport merges batch into the password, MFA and PIN stores and prints
+// a report. Entries whose name/account is already stored are left alone and
+// reported as conflicts. With dryRun nothing is written.
+func applyImport(batch *importBatch, dryRun bool) error {
+	passwords, err := loadPasswordStorage()
+	if err != nil {
+		return fmt.Errorf("error loading passwords: %v", err)
+	}
+	unlock, err := lockMFAStorage()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	mfa, err := loadMFAStorage()
+	if err != nil {
+		return fmt.Errorf("error loading MFA entries: %v", err)
+	}
+	pins, err := loadMPINConfig()
+	if err != nil {
+		return fmt.Errorf("error loading MPINs: %v", err)
+	}
+
+	var results []importResult
+	createdPasswords, createdMFA, createdPINs, conflicts := 0, 0, 0, 0
+
+	stored := len(passwords.Entries)
+	for _, entry := range batch.Passwords {
+		conflict := ""
+		for i, existing := range passwords.Entries {
+			if existing.Name == entry.Name && existing.Account == entry.Account {
+				conflict = "already stored"
+				if i >= stored {
+					conflict = "duplicate in this import"
+				} else if existing.Password == entry.Password {
+					conflict = "already stored with the same password"
+				}
+				break
+			}
+		}
+		if conflict != "" {
+			results = append(results, importResult{importConflict, "password", entry.Name, entry.Account, conflict})
+			conflicts++
+			continue
+		}
+		passwords.Entries = append(passwords.Entries, entry)
+		results = append(results, importResult{importCreate, "password", entry.Name, entry.Account, ""})
+		createdPasswords++
+	}
+
+	for _, entry := range batch.MFA {
+		if _, err := findMFAEntry(mfa, entry.Account, entry.Name); err == nil {
+			results = append(results, importResult{importConflict, "mfa", entry.Account, entry.Name, "already stored"})
+			conflicts++
+			continue
+		}
+		mfa.Entries = append(mfa.Entries, entry)
+		detail := ""
+		if entry.Type == mfaTypeSteam {
+			detail = "steam guard"
+		}
+		results = append(results, importResult{importCreate, "mfa", entry.Account, entry.Name, detail})
+		createdMFA++
+	}
+
+	for _, entry := range batch.PINs {
+		if existing, _ := findMPINEntry(pins, entry.Name, entry.Account); existing != nil {
+			results = append(results, importResult{importConflict, "pin", entry.Name, entry.Account, "already stored"})
+			conflicts++
+			continue
+		}
+		pins.Entries = append(pins.Entries, entry)
+		results = append(results, importResult{importCreate, "pin", entry.Name, entry.Account, entry.Kind})
+		createdPINs++
+	}
+
+	results = append(results, batch.Skipped...)
+
+	if !dryRun {
+		if createdPasswords > 0 {
+			if err := savePasswordStorage(passwords); err != nil {
+				return err
+			}
+		}
+		if createdMFA > 0 {
+			if err := saveMFAStorage(mfa); err != nil {
+				return err
+			}
+		}
+		if createdPINs > 0 {
+			if err := saveMPINConfig(pins); err != nil {
+				return err
+			}
+		}
+	}
+
+	title := batch.Source + " Import"
+	if dryRun {
+		title += " (dry run)"
+	}
+	fmt.Println(title + ":")
+	fmt.Println(strings.Repeat("=", len(title)+1))
+
+	if len(results) == 0 {
+		fmt.Println("Nothing to import")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tNAME\tACCOUNT\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Action, r.Kind, r.Name, r.Account, r.Detail)
+	}
+	w.Flush()
+
+	verb := "Created"
+	if dryRun {
+		verb = "Would create"
+	}
+	fmt.Printf("\n%s %d password(s), %d MFA entry(ies) and %d PIN(s); %d conflict(s), %d skipped\n",
+		verb, createdPasswords, createdMFA, createdPINs, conflicts, len(batch.Skipped))
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cazzano/password_manager_cli/otp"
+	"rsc.io/qr"
+)
+
+// entryCounterStore adapts a single stored MFA entry to otp.CounterStore so
+// the last accepted time step is persisted with the entry itself. It is not
+// safe for concurrent use on its own; ValidateMFA holds the MFA storage
+// lock around it, as every writer of the storage does.
+type entryCounterStore struct {
+	entry *MFAEntry
+}
+
+func (s entryCounterStore) LastCounter(id string) (int64, bool, error) {
+	if s.entry.LastCounter == nil {
+		return 0, false, nil
+	}
+	return *s.entry.LastCounter, true, nil
+}
+
+func (s entryCounterStore) SetLastCounter(id string, counter int64) error {
+	s.entry.LastCounter = &counter
+	return nil
+}
+
+// renderQR draws a QR code with Unicode half blocks, two modules per
+// character row, with the 4-module quiet zone ISO/IEC 18004 asks for.
+func renderQR(text string) (string, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode QR code: %v", err)
+	}
+
+	const quiet = 4
+	black := func(x, y int) bool {
+		return code.Black(x-quiet, y-quiet)
+	}
+
+	size := code.Size + 2*quiet
+	var b strings.Builder
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			top, bottom := black(x, y), black(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString(" ")
+			case top:
+				b.WriteString("▄")
+			case bottom:
+				b.WriteString("▀")
+			default:
+				b.WriteString("█")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// ProvisionMFA creates a new random secret for account/name, stores it and
+// prints the otpauth URI (and optionally a QR code) for the end user. An
+// existing secret is only replaced if force is set, since that locks out
+// the authenticator already enrolled with it.
+func ProvisionMFA(account, name, issuer string, size, period int, showQR, force bool) error {
+	if period <= 0 {
+		return fmt.Errorf("period must be greater than 0")
+	}
+
+	secret, err := otp.GenerateSecret(size)
+	if err != nil {
+		return err
+	}
+
+	// SetupMFA would print the secret in its debug output while validating
+	// it; a generated secret needs no validation.
+	if err := storeMFAEntry(MFAEntry{Account: account, Name: name, Secret: secret, Period: period}, force); err != nil {
+		return err
+	}
+
+	if issuer == "" {
+		issuer = account
+	}
+	uri := otp.KeyURI(issuer, name, secret, period, 6)
+
+	fmt.Printf("MFA provisioned for %s (%s)\n", name, account)
+	fmt.Printf("Secret: %s (%d bits)\n", secret, size*8)
+	fmt.Printf("URI: %s\n", uri)
+
+	if showQR {
+		code, err := renderQR(uri)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Print(code)
+	}
+
+	return nil
+}
+
+// ValidateMFA checks a code submitted for account/name, allowing drift
+// periods of clock skew either side. A code that has already been accepted,
+// or one older than the last accepted code, is rejected.
+func ValidateMFA(account, name, code string, drift int) (bool, error) {
+	if drift < 0 {
+		return false, fmt.Errorf("drift must not be negative")
+	}
+
+	// Hold the lock from load to save so two validations can't both
+	// accept the same code.
+	unlock, err := lockMFAStorage()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return false, err
+	}
+
+	entry, err := findMFAEntry(storage, account, name)
+	if err != nil {
+		return false, err
+	}
+
+	if entry.Type != "" && entry.Type != mfaTypeTOTP {
+		return false, fmt.Errorf("validation is only supported for TOTP entries")
+	}
+
+	validator := otp.NewValidator(entryCounterStore{entry: entry})
+	validator.Period = entry.Period
+	validator.Skew = drift
+
+	ok, err := validator.Validate(account+"/"+name, cleanSecret(entry.Secret), code, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		if err := saveMFAStorage(storage); err != nil {
+			return false, err
+		}
+	}
+	return ok, nil
+}
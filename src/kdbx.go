@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+)
+
+// KDBX 4 is the KeePass 2 database format: a plain header (cipher, KDF
+// parameters, seeds) followed by its SHA-256 and HMAC, then HMAC-protected
+// blocks of the encrypted, usually gzipped payload. The payload is an inner
+// header holding the key for protected values, followed by the XML tree.
+const (
+	kdbxSignature1 = 0x9AA2D903
+	kdbxSignature2 = 0xB54BFB67
+	kdbxVersion4   = 0x00040000
+	kdbxBlockSize  = 1024 * 1024
+
+	kdbxHeaderEnd         = 0
+	kdbxHeaderCipher      = 2
+	kdbxHeaderCompression = 3
+	kdbxHeaderMasterSeed  = 4
+	kdbxHeaderIV          = 7
+	kdbxHeaderKDF         = 11
+
+	kdbxInnerEnd       = 0
+	kdbxInnerStreamID  = 1
+	kdbxInnerStreamKey = 2
+	kdbxStreamChaCha20 = 3
+
+	// Cipher and KDF UUIDs.
+	kdbxCipherAES      = "\x31\xc1\xf2\xe6\xbf\x71\x43\x50\xbe\x58\x05\x21\x6a\xfc\x5a\xff"
+	kdbxCipherChaCha20 = "\xd6\x03\x8a\x2b\x8b\x6f\x4c\xb5\xa5\x24\x33\x9a\x31\xdb\xb5\x9a"
+	kdbxCipherTwofish  = "\xad\x68\xf2\x9f\x57\x6f\x4b\xb9\xa3\x6a\xd4\xaf\x96\x5f\x34\x6c"
+	kdbxKDFAES         = "\xc9\xd9\xf3\x9a\x62\x8a\x44\x60\xbf\x74\x0d\x08\xc1\x8a\x4f\xea"
+	kdbxKDFArgon2d     = "\xef\x63\x6d\xdf\x8c\x29\x44\x4b\x91\xf7\xa9\xa4\x03\xe3\x0a\x0c"
+	kdbxKDFArgon2id    = "\x9e\x29\x8b\x19\x56\xdb\x47\x73\xb2\x3d\xfc\x3e\xc6\xf0\xa1\xe6"
+
+	// Variant dictionary value types.
+	kdbxVariantUInt32    = 0x04
+	kdbxVariantUInt64    = 0x05
+	kdbxVariantByteArray = 0x42
+
+	// Argon2 settings for exported databases.
+	kdbxArgon2Iterations  = 3
+	kdbxArgon2Memory      = 64 * 1024 * 1024
+	kdbxArgon2Parallelism = 2
+	kdbxMaxArgon2Memory   = 2 * 1024 * 1024 * 1024
+)
+
+var kdbxCipherNames = map[string]string{
+	"aes":      kdbxCipherAES,
+	"chacha20": kdbxCipherChaCha20,
+}
+
+// kdbxEpoch is the zero point of KDBX 4 timestamps.
+var kdbxEpoch = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type kdbxHeader struct {
+	Cipher     string
+	Compressed bool
+	MasterSeed []byte
+	IV         []byte
+	KDF        map[string]any // Variant dictionary: "$UUID", "S", "I", "M", ...
+}
+
+// The XML payload. Only the parts mapped to our entry types are kept.
+type kdbxXML struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    kdbxMeta `xml:"Meta"`
+	Root    struct {
+		Groups []kdbxGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type kdbxMeta struct {
+	Generator         string                `xml:"Generator,omitempty"`
+	DatabaseName      string                `xml:"DatabaseName,omitempty"`
+	MemoryProtection  *kdbxMemoryProtection `xml:"MemoryProtection,omitempty"`
+	RecycleBinEnabled string                `xml:"RecycleBinEnabled,omitempty"`
+	RecycleBinUUID    string                `xml:"RecycleBinUUID,omitempty"`
+}
+
+type kdbxMemoryProtection struct {
+	ProtectTitle    string `xml:"ProtectTitle"`
+	ProtectUserName string `xml:"ProtectUserName"`
+	ProtectPassword string `xml:"ProtectPassword"`
+	ProtectURL      string `xml:"ProtectURL"`
+	ProtectNotes    string `xml:"ProtectNotes"`
+}
+
+type kdbxGroup struct {
+	UUID    string      `xml:"UUID"`
+	Name    string      `xml:"Name"`
+	Times   kdbxTimes   `xml:"Times"`
+	Entries []kdbxEntry `xml:"Entry"`
+	Groups  []kdbxGroup `xml:"Group"`
+}
+
+type kdbxEntry struct {
+	UUID    string       `xml:"UUID"`
+	Times   kdbxTimes    `xml:"Times"`
+	Strings []kdbxString `xml:"String"`
+}
+
+type kdbxString struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Protected string `xml:"Protected,attr,omitempty"`
+		Text      string `xml:",chardata"`
+	} `xml:"Value"`
+}
+
+type kdbxTimes struct {
+	CreationTime         string `xml:"CreationTime,omitempty"`
+	LastModificationTime string `xml:"LastModificationTime,omitempty"`
+	LastAccessTime       string `xml:"LastAccessTime,omitempty"`
+	ExpiryTime           string `xml:"ExpiryTime,omitempty"`
+	Expires              string `xml:"Expires,omitempty"`
+	UsageCount           int    `xml:"UsageCount"`
+	LocationChanged      string `xml:"LocationChanged,omitempty"`
+}
+
+// kdbxTime encodes t as KDBX 4 does: base64 of the little-endian seconds
+// since 0001-01-01 UTC.
+func kdbxTime(t time.Time) string {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(t.Unix()-kdbxEpoch.Unix()))
+	return base64.StdEncoding.EncodeToString(b[:])
+}
+
+// parseKDBXTime reads a KDBX 4 timestamp, or the ISO 8601 form used by
+// older files.
+func parseKDBXTime(value string) (time.Time, bool) {
+	if b, err := base64.StdEncoding.DecodeString(value); err == nil && len(b) == 8 {
+		seconds := int64(binary.LittleEndian.Uint64(b))
+		return time.Unix(kdbxEpoch.Unix()+seconds, 0).UTC(), true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+func newKDBXTimes(t time.Time) kdbxTimes {
+	stamp := kdbxTime(t)
+	return kdbxTimes{
+		CreationTime:         stamp,
+		LastModificationTime: stamp,
+		LastAccessTime:       stamp,
+		ExpiryTime:           stamp,
+		Expires:              "False",
+		LocationChanged:      stamp,
+	}
+}
+
+func newKDBXUUID() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (e kdbxEntry) fields() map[string]string {
+	fields := make(map[string]string, len(e.Strings))
+	for _, s := range e.Strings {
+		fields[s.Key] = s.Value.Text
+	}
+	return fields
+}
+
+func (e *kdbxEntry) set(key, value string, protected bool) {
+	s := kdbxString{Key: key}
+	s.Value.Text = value
+	if protected {
+		s.Value.Protected = "True"
+	}
+	e.Strings = append(e.Strings, s)
+}
+
+// subgroup returns the child group called name, creating it if needed.
+func (g *kdbxGroup) subgroup(name string, now time.Time) (*kdbxGroup, error) {
+	for i := range g.Groups {
+		if g.Groups[i].Name == name {
+			return &g.Groups[i], nil
+		}
+	}
+	uuid, err := newKDBXUUID()
+	if err != nil {
+		return nil, err
+	}
+	g.Groups = append(g.Groups, kdbxGroup{UUID: uuid, Name: name, Times: newKDBXTimes(now)})
+	return &g.Groups[len(g.Groups)-1], nil
+}
+
+func readKDBXVariantDictionary(data []byte) (map[string]any, error) {
+	errMalformed := fmt.Errorf("malformed KDF parameters")
+	if len(data) < 2 || data[1] != 1 {
+		return nil, errMalformed
+	}
+	data = data[2:]
+
+	dict := make(map[string]any)
+	for {
+		if len(data) < 1 {
+			return nil, errMalformed
+		}
+		kind := data[0]
+		if kind == 0 {
+			return dict, nil
+		}
+		if len(data) < 5 {
+			return nil, errMalformed
+		}
+		keyLen := int(binary.LittleEndian.Uint32(data[1:]))
+		if keyLen < 0 || len(data) < 9+keyLen {
+			return nil, errMalformed
+		}
+		key := string(data[5 : 5+keyLen])
+		valueLen := int(binary.LittleEndian.Uint32(data[5+keyLen:]))
+		data = data[9+keyLen:]
+		if valueLen < 0 || len(data) < valueLen {
+			return nil, errMalformed
+		}
+		value := data[:valueLen]
+		data = data[valueLen:]
+
+		switch {
+		case kind == kdbxVariantUInt32 && valueLen == 4:
+			dict[key] = binary.LittleEndian.Uint32(value)
+		case kind == kdbxVariantUInt64 && valueLen == 8:
+			dict[key] = binary.LittleEndian.Uint64(value)
+		default:
+			dict[key] = value
+		}
+	}
+}
+
+func writeKDBXVariantDictionary(dict map[string]any, order []string) []byte {
+	buf := []byte{0x00, 0x01}
+	for _, key := range order {
+		var kind byte
+		var value []byte
+		switch v := dict[key].(type) {
+		case uint32:
+			kind, value = kdbxVariantUInt32, binary.LittleEndian.AppendUint32(nil, v)
+		case uint64:
+			kind, value = kdbxVariantUInt64, binary.LittleEndian.AppendUint64(nil, v)
+		case []byte:
+			kind, value = kdbxVariantByteArray, v
+		}
+		buf = append(buf, kind)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(key)))
+		buf = append(buf, key...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(value)))
+		buf = append(buf, value...)
+	}
+	return append(buf, 0)
+}
+
+func readKDBXHeader(r *bytes.Reader) (*kdbxHeader, error) {
+	header := &kdbxHeader{}
+	for {
+		var id byte
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &id); err != nil {
+			return nil, fmt.Errorf("truncated header")
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil || int64(size) > int64(r.Len()) {
+			return nil, fmt.Errorf("truncated header")
+		}
+		data := make([]byte, size)
+		io.ReadFull(r, data)
+
+		switch id {
+		case kdbxHeaderEnd:
+			return header, nil
+		case kdbxHeaderCipher:
+			header.Cipher = string(data)
+		case kdbxHeaderCompression:
+			header.Compressed = len(data) == 4 && binary.LittleEndian.Uint32(data) == 1
+		case kdbxHeaderMasterSeed:
+			header.MasterSeed = data
+		case kdbxHeaderIV:
+			header.IV = data
+		case kdbxHeaderKDF:
+			kdf, err := readKDBXVariantDictionary(data)
+			if err != nil {
+				return nil, err
+			}
+			header.KDF = kdf
+		}
+	}
+}
+
+func writeKDBXHeaderField(buf []byte, id byte, data []byte) []byte {
+	buf = append(buf, id)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
+
+// kdbxTransformKey runs the header's KDF over the composite key, the
+// SHA-256 of the SHA-256 of the password.
+func kdbxTransformKey(header *kdbxHeader, password string) ([]byte, error) {
+	passwordHash := sha256.Sum256([]byte(password))
+	composite := sha256.Sum256(passwordHash[:])
+
+	uuid, _ := header.KDF["$UUID"].([]byte)
+	salt, _ := header.KDF["S"].([]byte)
+
+	switch string(uuid) {
+	case kdbxKDFAES:
+		rounds, _ := header.KDF["R"].(uint64)
+		block, err := aes.NewCipher(salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AES-KDF seed: %v", err)
+		}
+		key := composite
+		for i := uint64(0); i < rounds; i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+		transformed := sha256.Sum256(key[:])
+		return transformed[:], nil
+	case kdbxKDFArgon2d, kdbxKDFArgon2id:
+		iterations, _ := header.KDF["I"].(uint64)
+		memory, _ := header.KDF["M"].(uint64)
+		parallelism, _ := header.KDF["P"].(uint32)
+		version, _ := header.KDF["V"].(uint32)
+		if version != argon2Version {
+			return nil, fmt.Errorf("unsupported Argon2 version 0x%x", version)
+		}
+		if iterations == 0 || iterations > math.MaxUint32 || memory < 8*1024 || memory > kdbxMaxArgon2Memory || parallelism == 0 || parallelism > 255 {
+			return nil, fmt.Errorf("unsupported Argon2 parameters (%d iterations, %d MiB, parallelism %d)", iterations, memory/1024/1024, parallelism)
+		}
+		if string(uuid) == kdbxKDFArgon2d {
+			return argon2dKey(composite[:], salt, nil, nil, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+		}
+		return argon2.IDKey(composite[:], salt, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+	}
+	return nil, fmt.Errorf("unsupported key derivation function")
+}
+
+// kdbxHMACKey returns the HMAC-SHA256 key for block index, derived from
+// the database's base HMAC key.
+func kdbxHMACKey(baseKey []byte, index uint64) []byte {
+	h := sha512.New()
+	h.Write(binary.LittleEndian.AppendUint64(nil, index))
+	h.Write(baseKey)
+	return h.Sum(nil)
+}
+
+func kdbxBlockMAC(baseKey []byte, index uint64, data []byte) []byte {
+	mac := hmac.New(sha256.New, kdbxHMACKey(baseKey, index))
+	mac.Write(binary.LittleEndian.AppendUint64(nil, index))
+	mac.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func kdbxPayloadCipher(header *kdbxHeader, key []byte) (cipher.Block, error) {
+	switch header.Cipher {
+	case kdbxCipherAES:
+		return aes.NewCipher(key)
+	case kdbxCipherTwofish:
+		return twofish.NewCipher(key)
+	}
+	return nil, nil
+}
+
+func kdbxDecryptPayload(header *kdbxHeader, key, ciphertext []byte) ([]byte, error) {
+	if header.Cipher == kdbxCipherChaCha20 {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, header.IV)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ChaCha20 IV: %v", err)
+		}
+		plaintext := make([]byte, len(ciphertext))
+		stream.XORKeyStream(plaintext, ciphertext)
+		return plaintext, nil
+	}
+
+	block, err := kdbxPayloadCipher(header, key)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("unsupported cipher")
+	}
+	if len(header.IV) != block.BlockSize() || len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("malformed encrypted payload")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, header.IV).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func kdbxEncryptPayload(header *kdbxHeader, key, plaintext []byte) ([]byte, error) {
+	if header.Cipher == kdbxCipherChaCha20 {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, header.IV)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext := make([]byte, len(plaintext))
+		stream.XORKeyStream(ciphertext, plaintext)
+		return ciphertext, nil
+	}
+
+	block, err := kdbxPayloadCipher(header, key)
+	if err != nil {
+		return nil, err
+	}
+	padding := block.BlockSize() - len(plaintext)%block.BlockSize()
+	padded := append(bytes.Clone(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, header.IV).CryptBlocks(padded, padded)
+	return padded, nil
+}
+
+// kdbxInnerStream returns the ChaCha20 stream protected values are XORed
+// with, keyed by the SHA-512 of the inner header's stream key.
+func kdbxInnerStream(key []byte) (*chacha20.Cipher, error) {
+	hash := sha512.Sum512(key)
+	return chacha20.NewUnauthenticatedCipher(hash[:32], hash[32:44])
+}
+
+// kdbxTransformProtected rewrites the XML with every Protected="True" value
+// run through the inner stream, in document order as KeePass requires.
+// With reveal the base64 ciphertext is replaced by the plain text;
+// otherwise plain text is replaced by base64 ciphertext.
+func kdbxTransformProtected(data []byte, stream cipher.Stream, reveal bool) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var out bytes.Buffer
+	encoder := xml.NewEncoder(&out)
+
+	protected := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			protected = false
+			if t.Name.Local == "Value" {
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "Protected" && attr.Value == "True" {
+						protected = true
+					}
+				}
+			}
+		case xml.EndElement:
+			protected = false
+		case xml.CharData:
+			if protected {
+				if reveal {
+					value, err := base64.StdEncoding.DecodeString(string(t))
+					if err != nil {
+						return nil, fmt.Errorf("invalid protected value: %v", err)
+					}
+					stream.XORKeyStream(value, value)
+					token = xml.CharData(value)
+				} else {
+					value := []byte(t)
+					stream.XORKeyStream(value, value)
+					token = xml.CharData(base64.StdEncoding.EncodeToString(value))
+				}
+			}
+		}
+
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			return nil, fmt.Errorf("failed to rewrite XML: %v", err)
+		}
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// readKDBX decrypts a KDBX 4 database protected by password alone.
+func readKDBX(data []byte, password string) (*kdbxXML, error) {
+	r := bytes.NewReader(data)
+	var signature [3]uint32
+	if err := binary.Read(r, binary.LittleEndian, &signature); err != nil || signature[0] != kdbxSignature1 || signature[1] != kdbxSignature2 {
+		return nil, fmt.Errorf("not a KeePass database")
+	}
+	if major := signature[2] >> 16; major != 4 {
+		return nil, fmt.Errorf("KDBX version %d.%d is not supported; save the database in KDBX 4 format", major, signature[2]&0xffff)
+	}
+
+	header, err := readKDBXHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	headerBytes := data[:len(data)-r.Len()]
+
+	var headerHash, headerMAC [32]byte
+	if _, err := io.ReadFull(r, headerHash[:]); err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	if _, err := io.ReadFull(r, headerMAC[:]); err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	if sha256.Sum256(headerBytes) != headerHash {
+		return nil, fmt.Errorf("header checksum mismatch; the file is corrupted")
+	}
+	if len(header.MasterSeed) != 32 {
+		return nil, fmt.Errorf("invalid master seed")
+	}
+
+	transformed, err := kdbxTransformKey(header, password)
+	if err != nil {
+		return nil, err
+	}
+	seeded := append(bytes.Clone(header.MasterSeed), transformed...)
+	encKey := sha256.Sum256(seeded)
+	hmacKey := sha512.Sum512(append(seeded, 1))
+
+	mac := hmac.New(sha256.New, kdbxHMACKey(hmacKey[:], math.MaxUint64))
+	mac.Write(headerBytes)
+	if !hmac.Equal(mac.Sum(nil), headerMAC[:]) {
+		return nil, fmt.Errorf("wrong password or corrupted database")
+	}
+
+	var ciphertext []byte
+	for index := uint64(0); ; index++ {
+		var blockMAC [32]byte
+		var size uint32
+		if _, err := io.ReadFull(r, blockMAC[:]); err != nil {
+			return nil, fmt.Errorf("truncated block %d", index)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil || int64(size) > int64(r.Len()) {
+			return nil, fmt.Errorf("truncated block %d", index)
+		}
+		block := make([]byte, size)
+		io.ReadFull(r, block)
+		if !hmac.Equal(kdbxBlockMAC(hmacKey[:], index, block), blockMAC[:]) {
+			return nil, fmt.Errorf("block %d failed authentication; the file is corrupted", index)
+		}
+		if size == 0 {
+			break
+		}
+		ciphertext = append(ciphertext, block...)
+	}
+
+	payload, err := kdbxDecryptPayload(header, encKey[:], ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt database: %v", err)
+	}
+	if header.Compressed {
+		gz, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress database: %v", err)
+		}
+		if payload, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("failed to decompress database: %v", err)
+		}
+	}
+
+	var streamID uint32
+	var streamKey []byte
+	for {
+		if len(payload) < 5 {
+			return nil, fmt.Errorf("truncated inner header")
+		}
+		id, size := payload[0], int(binary.LittleEndian.Uint32(payload[1:]))
+		if size < 0 || len(payload) < 5+size {
+			return nil, fmt.Errorf("truncated inner header")
+		}
+		value := payload[5 : 5+size]
+		payload = payload[5+size:]
+
+		if id == kdbxInnerEnd {
+			break
+		}
+		switch id {
+		case kdbxInnerStreamID:
+			if size == 4 {
+				streamID = binary.LittleEndian.Uint32(value)
+			}
+		case kdbxInnerStreamKey:
+			streamKey = value
+		}
+	}
+	if streamID != kdbxStreamChaCha20 {
+		return nil, fmt.Errorf("unsupported inner stream cipher %d", streamID)
+	}
+
+	stream, err := kdbxInnerStream(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	plainXML, err := kdbxTransformProtected(bytes.TrimPrefix(payload, []byte("\xef\xbb\xbf")), stream, true)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &kdbxXML{}
+	if err := xml.Unmarshal(plainXML, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse database XML: %v", err)
+	}
+	return doc, nil
+}
+
+// writeKDBX encrypts doc as a gzipped KDBX 4.0 database with an Argon2id
+// key derived from password and the given payload cipher.
+func writeKDBX(doc *kdbxXML, password, cipherName string) ([]byte, error) {
+	cipherID, ok := kdbxCipherNames[cipherName]
+	if !ok {
+		return nil, fmt.Errorf("unknown cipher '%s' (use aes or chacha20)", cipherName)
+	}
+
+	ivSize := aes.BlockSize
+	if cipherID == kdbxCipherChaCha20 {
+		ivSize = chacha20.NonceSize
+	}
+	var random [4][]byte
+	for i, size := range []int{32, ivSize, 32, 64} {
+		b, err := randomBytes(size)
+		if err != nil {
+			return nil, err
+		}
+		random[i] = b
+	}
+	masterSeed, iv, salt, streamKey := random[0], random[1], random[2], random[3]
+
+	header := &kdbxHeader{
+		Cipher:     cipherID,
+		Compressed: true,
+		MasterSeed: masterSeed,
+		IV:         iv,
+		KDF: map[string]any{
+			"$UUID": []byte(kdbxKDFArgon2id),
+			"S":     salt,
+			"I":     uint64(kdbxArgon2Iterations),
+			"M":     uint64(kdbxArgon2Memory),
+			"P":     uint32(kdbxArgon2Parallelism),
+			"V":     uint32(argon2Version),
+		},
+	}
+
+	buf := binary.LittleEndian.AppendUint32(nil, kdbxSignature1)
+	buf = binary.LittleEndian.AppendUint32(buf, kdbxSignature2)
+	buf = binary.LittleEndian.AppendUint32(buf, kdbxVersion4)
+	buf = writeKDBXHeaderField(buf, kdbxHeaderCipher, []byte(cipherID))
+	buf = writeKDBXHeaderField(buf, kdbxHeaderCompression, binary.LittleEndian.AppendUint32(nil, 1))
+	buf = writeKDBXHeaderField(buf, kdbxHeaderMasterSeed, masterSeed)
+	buf = writeKDBXHeaderField(buf, kdbxHeaderIV, iv)
+	buf = writeKDBXHeaderField(buf, kdbxHeaderKDF, writeKDBXVariantDictionary(header.KDF, []string{"$UUID", "S", "I", "M", "P", "V"}))
+	buf = writeKDBXHeaderField(buf, kdbxHeaderEnd, []byte("\r\n\r\n"))
+
+	transformed, err := kdbxTransformKey(header, password)
+	if err != nil {
+		return nil, err
+	}
+	seeded := append(bytes.Clone(masterSeed), transformed...)
+	encKey := sha256.Sum256(seeded)
+	hmacKey := sha512.Sum512(append(seeded, 1))
+
+	headerHash := sha256.Sum256(buf)
+	mac := hmac.New(sha256.New, kdbxHMACKey(hmacKey[:], math.MaxUint64))
+	mac.Write(buf)
+	buf = append(buf, headerHash[:]...)
+	buf = append(buf, mac.Sum(nil)...)
+
+	plainXML, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal database XML: %v", err)
+	}
+	stream, err := kdbxInnerStream(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	protectedXML, err := kdbxTransformProtected(append([]byte(xml.Header), plainXML...), stream, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	inner := writeKDBXHeaderField(nil, kdbxInnerStreamID, binary.LittleEndian.AppendUint32(nil, kdbxStreamChaCha20))
+	inner = writeKDBXHeaderField(inner, kdbxInnerStreamKey, streamKey)
+	inner = writeKDBXHeaderField(inner, kdbxInnerEnd, nil)
+	gz.Write(inner)
+	gz.Write(protectedXML)
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress database: %v", err)
+	}
+
+	ciphertext, err := kdbxEncryptPayload(header, encKey[:], payload.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	for index := uint64(0); ; index++ {
+		block := ciphertext[:min(kdbxBlockSize, len(ciphertext))]
+		ciphertext = ciphertext[len(block):]
+		buf = append(buf, kdbxBlockMAC(hmacKey[:], index, block)...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(block)))
+		buf = append(buf, block...)
+		if len(block) == 0 {
+			break
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKDBXRoundTrip(t *testing.T) {
+	now := time.Now()
+	entry := kdbxEntry{UUID: "AAAAAAAAAAAAAAAAAAAAAA==", Times: newKDBXTimes(now)}
+	entry.set("Title", "example.com", false)
+	entry.set("UserName", "alice", false)
+	entry.set("Password", "s3cret <&> \"pass\"", true)
+	entry.set("Notes", "line one\nline two", false)
+
+	root := kdbxGroup{UUID: "AQEBAQEBAQEBAQEBAQEBAQ==", Name: "Root", Times: newKDBXTimes(now)}
+	group, err := root.subgroup("Work", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group.Entries = append(group.Entries, entry)
+
+	doc := &kdbxXML{Meta: kdbxMeta{Generator: keepassGenerator}}
+	doc.Root.Groups = []kdbxGroup{root}
+
+	for _, cipherName := range []string{"aes", "chacha20"} {
+		data, err := writeKDBX(doc, "correct horse", cipherName)
+		if err != nil {
+			t.Fatalf("%s: %v", cipherName, err)
+		}
+
+		got, err := readKDBX(data, "correct horse")
+		if err != nil {
+			t.Fatalf("%s: %v", cipherName, err)
+		}
+		if got.Meta.Generator != keepassGenerator {
+			t.Errorf("%s: generator = %q", cipherName, got.Meta.Generator)
+		}
+		if len(got.Root.Groups) != 1 || len(got.Root.Groups[0].Groups) != 1 || len(got.Root.Groups[0].Groups[0].Entries) != 1 {
+			t.Fatalf("%s: group tree not preserved: %+v", cipherName, got.Root)
+		}
+		work := got.Root.Groups[0].Groups[0]
+		if work.Name != "Work" {
+			t.Errorf("%s: group name = %q", cipherName, work.Name)
+		}
+		fields := work.Entries[0].fields()
+		for key, want := range entry.fields() {
+			if fields[key] != want {
+				t.Errorf("%s: %s = %q, want %q", cipherName, key, fields[key], want)
+			}
+		}
+
+		for _, s := range work.Entries[0].Strings {
+			if s.Key == "Password" && s.Value.Protected != "True" {
+				t.Errorf("%s: password is not marked protected", cipherName)
+			}
+		}
+
+		if _, err := readKDBX(data, "wrong horse"); err == nil {
+			t.Errorf("%s: wrong password accepted", cipherName)
+		}
+	}
+}
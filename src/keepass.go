@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cazzano/password_manager_cli/otp"
+)
+
+// Exported PINs and MFA secrets without a matching password live in these
+// top-level groups, so an exported database imports back into the same
+// stores.
+const (
+	keepassPINGroup   = "PINs"
+	keepassMFAGroup   = "MFA"
+	keepassPINKindKey = "PIN Kind"
+	keepassGenerator  = "password_manager_cli"
+)
+
+type keepassField struct {
+	Key       string
+	Value     string
+	Protected bool
+}
+
+// keepassStandardFields are the entry strings mapped to PasswordEntry
+// fields; any other string is kept in the notes.
+var keepassStandardFields = map[string]bool{
+	"Title": true, "UserName": true, "Password": true, "URL": true, "Notes": true,
+	"otp": true, "TOTP Seed": true, "TOTP Settings": true, keepassPINKindKey: true,
+}
+
+// keepassTOTP returns the entry's TOTP field: the otpauth URI in "otp", or
+// the older KeePassXC "TOTP Seed" and "TOTP Settings" ("30;6", or "30;S"
+// for Steam) pair.
+func keepassTOTP(fields map[string]string, user string) string {
+	if fields["otp"] != "" {
+		return fields["otp"]
+	}
+	seed := fields["TOTP Seed"]
+	if seed == "" {
+		return ""
+	}
+
+	period, digits := 30, "6"
+	if settings := fields["TOTP Settings"]; settings != "" {
+		fmt.Sscanf(settings, "%d;%s", &period, &digits)
+	}
+	if digits == "S" {
+		return "steam://" + seed
+	}
+	if period == 30 && digits == "6" {
+		return seed
+	}
+	n := 0
+	fmt.Sscanf(digits, "%d", &n)
+	return otp.KeyURI("", user, strings.ToUpper(strings.ReplaceAll(seed, " ", "")), period, n)
+}
+
+func keepassNotes(fields map[string]string) string {
+	var lines []string
+	if fields["Notes"] != "" {
+		lines = append(lines, fields["Notes"])
+	}
+
+	var extra []string
+	for key := range fields {
+		if !keepassStandardFields[key] && !strings.HasPrefix(key, "URL ") && fields[key] != "" {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		lines = append(lines, fmt.Sprintf("%s: %s", key, fields[key]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addKeePassGroup adds the entries of group and its subgroups. Entries in
+// the top-level PIN group are read as PINs only if this tool wrote the
+// database (ours) or they carry a PIN kind; a "PINs" group made in
+// KeePass itself is imported as passwords.
+func addKeePassGroup(batch *importBatch, group kdbxGroup, path, recycleBin string, ours bool) {
+	for _, e := range group.Entries {
+		fields := e.fields()
+		title, user, password := fields["Title"], fields["UserName"], fields["Password"]
+
+		kind, hasKind := fields[keepassPINKindKey]
+		if path == keepassPINGroup && (ours || hasKind) {
+			if err := validatePIN(password, kind); err != nil {
+				batch.skip("pin", title, user, err.Error())
+				continue
+			}
+			batch.PINs = append(batch.PINs, MPINEntry{Name: title, Account: user, PIN: password, Kind: kind})
+			continue
+		}
+
+		entry := PasswordEntry{
+			Name:     title,
+			Account:  user,
+			Password: password,
+			Group:    path,
+			Notes:    keepassNotes(fields),
+		}
+		if fields["URL"] != "" {
+			entry.URIs = append(entry.URIs, fields["URL"])
+		}
+		for i := 2; fields[fmt.Sprintf("URL %d", i)] != ""; i++ {
+			entry.URIs = append(entry.URIs, fields[fmt.Sprintf("URL %d", i)])
+		}
+		if t, ok := parseKDBXTime(e.Times.LastModificationTime); ok {
+			entry.UpdatedAt = &t
+		}
+		batch.addLogin(entry, keepassTOTP(fields, user))
+	}
+
+	for _, sub := range group.Groups {
+		if recycleBin != "" && sub.UUID == recycleBin {
+			continue
+		}
+		subPath := sub.Name
+		if path != "" {
+			subPath = path + "/" + sub.Name
+		}
+		addKeePassGroup(batch, sub, subPath, recycleBin, ours)
+	}
+}
+
+// ImportKeePass imports a password-protected KDBX 4 database. Group paths
+// below the root are kept on the password entries; entries in the
+// recycle bin are ignored.
+func ImportKeePass(path string, dryRun bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read database: %v", err)
+	}
+
+	password, err := promptSecret("Database password: ")
+	if err != nil {
+		return err
+	}
+	doc, err := readKDBX(data, password)
+	if err != nil {
+		return err
+	}
+	return applyImport(keepassBatch(doc), dryRun)
+}
+
+func keepassBatch(doc *kdbxXML) *importBatch {
+	recycleBin := ""
+	if doc.Meta.RecycleBinEnabled == "True" {
+		recycleBin = doc.Meta.RecycleBinUUID
+	}
+
+	batch := &importBatch{Source: "KeePass"}
+	for _, root := range doc.Root.Groups {
+		addKeePassGroup(batch, root, "", recycleBin, doc.Meta.Generator == keepassGenerator)
+	}
+	return batch
+}
+
+// ExportKeePass writes every password, MFA secret and PIN to a new KDBX 4
+// database protected by a password entered at the prompt. MFA secrets are
+// stored in the otp field of the matching password entry.
+func ExportKeePass(path, cipherName string) error {
+	if _, ok := kdbxCipherNames[cipherName]; !ok {
+		return fmt.Errorf("unknown cipher '%s' (use aes or chacha20)", cipherName)
+	}
+
+	passwords, err := loadPasswordStorage()
+	if err != nil {
+		return fmt.Errorf("error loading passwords: %v", err)
+	}
+	mfa, err := loadMFAStorage()
+	if err != nil {
+		return fmt.Errorf("error loading MFA entries: %v", err)
+	}
+	pins, err := loadMPINConfig()
+	if err != nil {
+		return fmt.Errorf("error loading MPINs: %v", err)
+	}
+
+	password, ok, err := promptConfirmedSecret("New database password: ", "Repeat password: ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("passwords do not match")
+	}
+	if password == "" {
+		return fmt.Errorf("database password cannot be empty")
+	}
+
+	now := time.Now()
+	rootUUID, err := newKDBXUUID()
+	if err != nil {
+		return err
+	}
+	root := kdbxGroup{UUID: rootUUID, Name: "Root", Times: newKDBXTimes(now)}
+
+	addEntry := func(groupPath string, updated time.Time, fields []keepassField) error {
+		group := &root
+		for _, name := range strings.Split(groupPath, "/") {
+			if name == "" {
+				continue
+			}
+			if group, err = group.subgroup(name, now); err != nil {
+				return err
+			}
+		}
+		uuid, err := newKDBXUUID()
+		if err != nil {
+			return err
+		}
+		entry := kdbxEntry{UUID: uuid, Times: newKDBXTimes(updated)}
+		for _, f := range fields {
+			if f.Value != "" || f.Key == "Title" || f.Key == "UserName" || f.Key == "Password" {
+				entry.set(f.Key, f.Value, f.Protected)
+			}
+		}
+		group.Entries = append(group.Entries, entry)
+		return nil
+	}
+
+	usedMFA := make(map[int]bool)
+	for _, p := range passwords.Entries {
+		totp := ""
+		for i, m := range mfa.Entries {
+			if !usedMFA[i] && m.Account == p.Name && m.Name == p.Account {
+				totp = mfaEntryURI(m)
+				usedMFA[i] = true
+				break
+			}
+		}
+		fields := []keepassField{
+			{"Title", p.Name, false}, {"UserName", p.Account, false}, {"Password", p.Password, true},
+			{"Notes", p.Notes, false}, {"otp", totp, false},
+		}
+		for i, uri := range p.URIs {
+			key := "URL"
+			if i > 0 {
+				key = fmt.Sprintf("URL %d", i+1)
+			}
+			fields = append(fields, keepassField{key, uri, false})
+		}
+		updated := now
+		if p.UpdatedAt != nil {
+			updated = *p.UpdatedAt
+		}
+		if err := addEntry(p.Group, updated, fields); err != nil {
+			return err
+		}
+	}
+
+	for i, m := range mfa.Entries {
+		if usedMFA[i] {
+			continue
+		}
+		fields := []keepassField{{"Title", m.Account, false}, {"UserName", m.Name, false}, {"Password", "", true}, {"otp", mfaEntryURI(m), false}}
+		if err := addEntry(keepassMFAGroup, now, fields); err != nil {
+			return err
+		}
+	}
+
+	for _, pin := range pins.Entries {
+		fields := []keepassField{{"Title", pin.Name, false}, {"UserName", pin.Account, false}, {"Password", pin.PIN, true}, {keepassPINKindKey, pin.Kind, false}}
+		if err := addEntry(keepassPINGroup, now, fields); err != nil {
+			return err
+		}
+	}
+
+	doc := &kdbxXML{Meta: kdbxMeta{
+		Generator:    keepassGenerator,
+		DatabaseName: "Passwords",
+		MemoryProtection: &kdbxMemoryProtection{
+			ProtectTitle:    "False",
+			ProtectUserName: "False",
+			ProtectPassword: "True",
+			ProtectURL:      "False",
+			ProtectNotes:    "False",
+		},
+		RecycleBinEnabled: "False",
+	}}
+	doc.Root.Groups = []kdbxGroup{root}
+
+	data, err := writeKDBX(doc, password, cipherName)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create database: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write database: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write database: %v", err)
+	}
+
+	fmt.Printf("Exported %d password(s), %d MFA entry(ies) and %d PIN(s) to %s\n",
+		len(passwords.Entries), len(mfa.Entries), len(pins.Entries), path)
+	return nil
+}
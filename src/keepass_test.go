@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddKeePassGroupPINs(t *testing.T) {
+	pin := kdbxEntry{}
+	pin.set("Title", "bank", false)
+	pin.set("UserName", "alice", false)
+	pin.set("Password", "4821", true)
+
+	kinded := kdbxEntry{}
+	kinded.set("Title", "phone", false)
+	kinded.set("UserName", "alice", false)
+	kinded.set("Password", "1739", true)
+	kinded.set(keepassPINKindKey, "sim", false)
+
+	group := kdbxGroup{Name: keepassPINGroup, Entries: []kdbxEntry{pin, kinded}}
+
+	tests := []struct {
+		ours      bool
+		pins      int
+		passwords int
+	}{
+		{true, 2, 0},
+		{false, 1, 1},
+	}
+	for _, tt := range tests {
+		batch := &importBatch{}
+		addKeePassGroup(batch, group, keepassPINGroup, "", tt.ours)
+		if len(batch.PINs) != tt.pins || len(batch.Passwords) != tt.passwords {
+			t.Errorf("ours=%v: got %d PIN(s) and %d password(s), want %d and %d",
+				tt.ours, len(batch.PINs), len(batch.Passwords), tt.pins, tt.passwords)
+		}
+	}
+}
+
+// The fixtures are KeePassXC-style databases written by
+// testdata/gen_kdbx.js with the password "correct horse".
+func TestImportKeePassXCDatabase(t *testing.T) {
+	for _, name := range []string{"keepassxc_aeskdf.kdbx", "keepassxc_argon2d.kdbx"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readKDBX(data, "wrong horse"); err == nil {
+				t.Error("wrong password accepted")
+			}
+			doc, err := readKDBX(data, "correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Meta.Generator != "KeePassXC" {
+				t.Errorf("generator = %q", doc.Meta.Generator)
+			}
+
+			batch := keepassBatch(doc)
+
+			updated := time.Date(2024, 3, 5, 10, 20, 30, 0, time.UTC)
+			forumUpdated := time.Date(2024, 2, 2, 2, 2, 2, 0, time.UTC)
+			want := []PasswordEntry{
+				{
+					Name: "example.com", Account: "alice", Password: "Tr0ub4dor&3",
+					Notes: "first line\nsecond line\nSecurity question: first pet",
+					URIs:  []string{"https://example.com/login"}, UpdatedAt: &updated,
+				},
+				{
+					Name: "forum", Account: "bob", Password: "correct-staple", Group: "Internet/Social",
+					URIs: []string{"https://forum.example"}, UpdatedAt: &forumUpdated,
+				},
+			}
+			for i := range want {
+				want[i].Length = characterCount(want[i].Password)
+				want[i].Source = "keepass"
+				want[i].Config = "imported from KeePass"
+			}
+			if !reflect.DeepEqual(batch.Passwords, want) {
+				t.Errorf("passwords:\n got %+v\nwant %+v", batch.Passwords, want)
+			}
+
+			wantMFA := []MFAEntry{
+				{Account: "example.com", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: 30},
+				{Account: "forum", Name: "bob", Secret: "JBSWY3DPEHPK3PXP", Type: mfaTypeSteam, Period: steamGuardPeriod},
+			}
+			if !reflect.DeepEqual(batch.MFA, wantMFA) {
+				t.Errorf("MFA:\n got %+v\nwant %+v", batch.MFA, wantMFA)
+			}
+
+			wantSkipped := []importResult{{importSkip, "password", "Wifi", "", "no username"}}
+			if !reflect.DeepEqual(batch.Skipped, wantSkipped) {
+				t.Errorf("skipped = %+v, want %+v", batch.Skipped, wantSkipped)
+			}
+		})
+	}
+}
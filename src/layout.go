@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// keyboardLayout lists the characters a layout produces with a single key
+// press, with or without Shift. Dead keys and AltGr combinations are left
+// out: they are what fails on KVM consoles and unfamiliar keyboards.
+type keyboardLayout struct {
+	unshifted string
+	shifted   string
+}
+
+var keyboardLayouts = map[string]keyboardLayout{
+	"us": {
+		unshifted: "`1234567890-=qwertyuiop[]\\asdfghjkl;'zxcvbnm,./",
+		shifted:   "~!@#$%^&*()_+QWERTYUIOP{}|ASDFGHJKL:\"ZXCVBNM<>?",
+	},
+	"uk": {
+		unshifted: "`1234567890-=qwertyuiop[]asdfghjkl;'#\\zxcvbnm,./",
+		shifted:   "¬!\"£$%^&*()_+QWERTYUIOP{}ASDFGHJKL:@~|ZXCVBNM<>?",
+	},
+	"de": {
+		unshifted: "1234567890ßqwertzuiopü+asdfghjklöä#<yxcvbnm,.-",
+		shifted:   "°!\"§$%&/()=?QWERTZUIOPÜ*ASDFGHJKLÖÄ'>YXCVBNM;:_",
+	},
+	"fr": {
+		unshifted: "²&é\"'(-è_çà)=azertyuiop$qsdfghjklmù*<wxcvbn,;:!",
+		shifted:   "1234567890°+AZERTYUIOP£QSDFGHJKLM%µ>WXCVBN?./§",
+	},
+}
+
+func layoutNames() []string {
+	names := make([]string, 0, len(keyboardLayouts))
+	for name := range keyboardLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseLayouts parses a comma-separated --layout value.
+func parseLayouts(value string) ([]string, error) {
+	var layouts []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := keyboardLayouts[name]; !ok {
+			return nil, fmt.Errorf("unknown keyboard layout '%s' (use %s)", name, strings.Join(layoutNames(), ", "))
+		}
+		layouts = append(layouts, name)
+	}
+	return layouts, nil
+}
+
+// keepTypeable removes the characters of chars that can't be typed directly
+// on every one of layouts.
+func keepTypeable(chars string, layouts []string) string {
+	return strings.Map(func(r rune) rune {
+		for _, name := range layouts {
+			layout := keyboardLayouts[name]
+			if !strings.ContainsRune(layout.unshifted, r) && !strings.ContainsRune(layout.shifted, r) {
+				return -1
+			}
+		}
+		return r
+	}, chars)
+}
+
+// isShiftedOn reports whether c needs Shift on the layout. Characters the
+// layout doesn't have count as unshifted.
+func isShiftedOn(layout string, c string) bool {
+	return strings.Contains(keyboardLayouts[layout].shifted, c)
+}
+
+// shiftToggles counts how often Shift goes down or up while typing password
+// on layout, starting with Shift released.
+func shiftToggles(password []string, layout string) int {
+	toggles := 0
+	shifted := false
+	for _, c := range password {
+		if s := isShiftedOn(layout, c); s != shifted {
+			toggles++
+			shifted = s
+		}
+	}
+	return toggles
+}
+
+// shiftLimitedCount returns how many strings of length over charset need at
+// most maxToggles Shift toggles on layout, for entropy calculations.
+func shiftLimitedCount(charset []string, length int, layout string, maxToggles int) float64 {
+	shifted := 0
+	for _, c := range charset {
+		if isShiftedOn(layout, c) {
+			shifted++
+		}
+	}
+	unshifted := float64(len(charset) - shifted)
+
+	// count[state][t] is the number of prefixes ending with Shift in state
+	// (0 up, 1 down) after t toggles.
+	count := [2][]float64{make([]float64, maxToggles+1), make([]float64, maxToggles+1)}
+	count[0][0] = 1
+	for i := 0; i < length; i++ {
+		next := [2][]float64{make([]float64, maxToggles+1), make([]float64, maxToggles+1)}
+		for t := 0; t <= maxToggles; t++ {
+			next[0][t] += count[0][t] * unshifted
+			next[1][t] += count[1][t] * float64(shifted)
+			if t < maxToggles {
+				next[1][t+1] += count[0][t] * float64(shifted)
+				next[0][t+1] += count[1][t] * unshifted
+			}
+		}
+		count = next
+	}
+
+	total := 0.0
+	for t := 0; t <= maxToggles; t++ {
+		total += count[0][t] + count[1][t]
+	}
+	return total
+}
+
+// compositions is the number of ways to split n characters into k
+// non-empty runs.
+func compositions(n, k int) *big.Int {
+	if n == 0 && k == 0 {
+		return big.NewInt(1)
+	}
+	if k <= 0 || n < k {
+		return new(big.Int)
+	}
+	return new(big.Int).Binomial(int64(n-1), int64(k-1))
+}
+
+// randomRuns splits n into k non-empty runs, uniformly over all such
+// splits.
+func randomRuns(n, k int) ([]int, error) {
+	if k == 0 {
+		return nil, nil
+	}
+	cuts := make([]int, n-1)
+	for i := range cuts {
+		cuts[i] = i + 1
+	}
+	for i := 0; i < k-1; i++ {
+		j, err := randomInt(len(cuts) - i)
+		if err != nil {
+			return nil, err
+		}
+		cuts[i], cuts[i+j] = cuts[i+j], cuts[i]
+	}
+	cuts = append(cuts[:k-1:k-1], n)
+	sort.Ints(cuts)
+
+	runs := make([]int, k)
+	prev := 0
+	for i, cut := range cuts {
+		runs[i], prev = cut-prev, cut
+	}
+	return runs, nil
+}
+
+// arrangeShift reorders password so typing it on layout toggles Shift at
+// most maxToggles times. The characters are split into runs of shifted and
+// unshifted ones; the run layout is picked uniformly from all layouts
+// within the budget, and each group keeps its (already random) order.
+func arrangeShift(password []string, layout string, maxToggles int) ([]string, error) {
+	var unshifted, shifted []string
+	for _, c := range password {
+		if isShiftedOn(layout, c) {
+			shifted = append(shifted, c)
+		} else {
+			unshifted = append(unshifted, c)
+		}
+	}
+	u, s := len(unshifted), len(shifted)
+
+	// Typing starts with Shift released, so a password starting with a
+	// shifted run costs one toggle per run and one starting unshifted
+	// costs one per run after the first.
+	type runLayout struct {
+		startShifted bool
+		runs         int
+	}
+	var layouts []runLayout
+	var weights []*big.Int
+	total := new(big.Int)
+	for runs := 1; runs <= u+s; runs++ {
+		for _, startShifted := range []bool{false, true} {
+			toggles := runs - 1
+			shiftedRuns, unshiftedRuns := runs/2, (runs+1)/2
+			if startShifted {
+				toggles = runs
+				shiftedRuns, unshiftedRuns = unshiftedRuns, shiftedRuns
+			}
+			if toggles > maxToggles {
+				continue
+			}
+			w := new(big.Int).Mul(compositions(u, unshiftedRuns), compositions(s, shiftedRuns))
+			if w.Sign() > 0 {
+				layouts = append(layouts, runLayout{startShifted, runs})
+				weights = append(weights, w)
+				total.Add(total, w)
+			}
+		}
+	}
+	if total.Sign() == 0 {
+		return nil, fmt.Errorf("no arrangement needs at most %d Shift toggles", maxToggles)
+	}
+
+	pick, err := randomBigInt(total)
+	if err != nil {
+		return nil, err
+	}
+	chosen := layouts[len(layouts)-1]
+	for i, w := range weights {
+		if pick.Sub(pick, w); pick.Sign() < 0 {
+			chosen = layouts[i]
+			break
+		}
+	}
+
+	shiftedRuns, unshiftedRuns := chosen.runs/2, (chosen.runs+1)/2
+	if chosen.startShifted {
+		shiftedRuns, unshiftedRuns = unshiftedRuns, shiftedRuns
+	}
+	shiftedLengths, err := randomRuns(s, shiftedRuns)
+	if err != nil {
+		return nil, err
+	}
+	unshiftedLengths, err := randomRuns(u, unshiftedRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	arranged := make([]string, 0, len(password))
+	inShifted := chosen.startShifted
+	for len(shiftedLengths) > 0 || len(unshiftedLengths) > 0 {
+		if inShifted {
+			arranged = append(arranged, shifted[:shiftedLengths[0]]...)
+			shifted, shiftedLengths = shifted[shiftedLengths[0]:], shiftedLengths[1:]
+		} else {
+			arranged = append(arranged, unshifted[:unshiftedLengths[0]]...)
+			unshifted, unshiftedLengths = unshifted[unshiftedLengths[0]:], unshiftedLengths[1:]
+		}
+		inShifted = !inShifted
+	}
+	return arranged, nil
+}
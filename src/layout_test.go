@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestGeneratePasswordShiftToggles(t *testing.T) {
+	for _, layout := range []string{"us", "de", "fr"} {
+		for maxToggles := 1; maxToggles <= 3; maxToggles++ {
+			opts := PasswordOptions{
+				Length:          64,
+				SmallAlpha:      true,
+				LargeAlpha:      true,
+				Digits:          true,
+				SpecialChars:    defaultSpecialChars,
+				Layouts:         []string{layout},
+				Typeable:        true,
+				MaxShiftToggles: maxToggles,
+			}
+			for i := 0; i < 50; i++ {
+				password, err := generatePassword(opts)
+				if err != nil {
+					t.Fatalf("%s, %d toggles: %v", layout, maxToggles, err)
+				}
+				chars := splitCharacters(password)
+				if len(chars) != opts.Length {
+					t.Fatalf("%s: got %d characters, want %d", password, len(chars), opts.Length)
+				}
+				if toggles := shiftToggles(chars, layout); toggles > maxToggles {
+					t.Fatalf("%s on %s needs %d Shift toggles, limit is %d", password, layout, toggles, maxToggles)
+				}
+			}
+		}
+	}
+}
+
+func TestArrangeShiftKeepsCharacters(t *testing.T) {
+	password := splitCharacters("aB1!cD2@eF3#")
+	arranged, err := arrangeShift(append([]string(nil), password...), "us", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toggles := shiftToggles(arranged, "us"); toggles > 1 {
+		t.Fatalf("%v needs %d toggles", arranged, toggles)
+	}
+
+	counts := make(map[string]int)
+	for _, c := range password {
+		counts[c]++
+	}
+	for _, c := range arranged {
+		counts[c]--
+	}
+	for c, n := range counts {
+		if n != 0 {
+			t.Fatalf("character %q count changed by %d", c, -n)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 func main() {
@@ -21,10 +23,42 @@ func main() {
 		handleList()
 	case "generate":
 		handleGenerate()
+	case "watch":
+		handleWatch()
+	case "provision-mfa":
+		handleProvisionMFA()
+	case "validate-mfa":
+		handleValidateMFA()
+	case "add-recovery":
+		handleAddRecovery()
+	case "use-recovery":
+		handleUseRecovery()
+	case "list-recovery":
+		handleListRecovery()
 	case "add-pass":
 		handleAddPassword()
 	case "get-pass":
 		handleGetPasswords()
+	case "gen":
+		handleGen()
+	case "policy":
+		handlePolicy()
+	case "rotate":
+		handleRotate()
+	case "spectre":
+		handleSpectre()
+	case "audit":
+		handleAudit()
+	case "breach-check":
+		handleBreachCheck()
+	case "import":
+		handleImport()
+	case "export":
+		handleExport()
+	case "share":
+		handleShare()
+	case "receive":
+		handleReceive()
 	case "add-mpin":
 		handleAddMPIN()
 	case "get-mpin":
@@ -44,6 +78,7 @@ func handleSetupMFA() {
 	name := fs.String("name", "", "Name/email (required)")
 	key := fs.String("k", "", "Secret key (required)")
 	seconds := fs.Int("s", 30, "Time step in seconds (default: 30)")
+	mfaType := fs.String("type", "totp", "Code type: totp or steam")
 
 	fs.Parse(os.Args[2:])
 
@@ -53,7 +88,7 @@ func handleSetupMFA() {
 		os.Exit(1)
 	}
 
-	err := SetupMFA(*account, *name, *key, *seconds)
+	err := SetupMFA(*account, *name, *key, *seconds, *mfaType)
 	if err != nil {
 		fmt.Printf("Error setting up MFA: %v\n", err)
 		os.Exit(1)
@@ -75,9 +110,25 @@ func handleList() {
 	}
 
 	fmt.Println("MFA Accounts:")
+	var warnings []string
 	for _, entry := range entries {
-		fmt.Printf("  Account: %s, Name: %s, Period: %ds\n", 
-			entry.Account, entry.Name, entry.Period)
+		if len(entry.RecoveryCodes) > 0 {
+			fmt.Printf("  Account: %s, Name: %s, Period: %ds, Recovery codes: %d/%d unused\n",
+				entry.Account, entry.Name, entry.Period, unusedRecoveryCodes(entry), len(entry.RecoveryCodes))
+		} else {
+			fmt.Printf("  Account: %s, Name: %s, Period: %ds\n", 
+				entry.Account, entry.Name, entry.Period)
+		}
+		if warning := recoveryWarning(entry); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if len(warnings) > 0 {
+		fmt.Println()
+		for _, warning := range warnings {
+			fmt.Println(warning)
+		}
 	}
 }
 
@@ -85,6 +136,9 @@ func handleGenerate() {
 	fs := flag.NewFlagSet("generate", flag.ExitOnError)
 	account := fs.String("account", "", "Account name (required)")
 	name := fs.String("name", "", "Name/email (required)")
+	showNext := fs.Bool("next", false, "Also show the code for the following period")
+	minRemaining := fs.Int("min-remaining", 0, "Wait for a new period if fewer than this many seconds remain")
+	at := fs.String("at", "", "Compute the code for this RFC3339 timestamp instead of now")
 
 	fs.Parse(os.Args[2:])
 
@@ -94,6 +148,33 @@ func handleGenerate() {
 		os.Exit(1)
 	}
 
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			fmt.Printf("Error: invalid --at timestamp: %v\n", err)
+			os.Exit(1)
+		}
+
+		code, remaining, err := GenerateMFAAt(*account, *name, t)
+		if err != nil {
+			fmt.Printf("Error generating MFA code: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("MFA Code at %s: %s (valid for %d more seconds)\n", t.Format(time.RFC3339), code, remaining)
+
+		if *showNext {
+			printNextMFACode(*account, *name, t.Add(time.Duration(remaining)*time.Second))
+		}
+		return
+	}
+
+	if *minRemaining > 0 {
+		if err := WaitForFreshMFA(*account, *name, *minRemaining); err != nil {
+			fmt.Printf("Error waiting for MFA code: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	code, remaining, err := GenerateMFA(*account, *name)
 	if err != nil {
 		fmt.Printf("Error generating MFA code: %v\n", err)
@@ -101,17 +182,331 @@ func handleGenerate() {
 	}
 
 	fmt.Printf("MFA Code: %s (valid for %d seconds)\n", code, remaining)
+
+	if *showNext {
+		printNextMFACode(*account, *name, time.Now().Add(time.Duration(remaining)*time.Second))
+	}
+}
+
+func printNextMFACode(account, name string, start time.Time) {
+	code, remaining, err := GenerateMFAAt(account, name, start)
+	if err != nil {
+		fmt.Printf("Error generating next MFA code: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Next Code: %s (from %s, valid for %d seconds)\n", code, start.Format("15:04:05"), remaining)
+}
+
+func handleWatch() {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	filter := fs.String("filter", "", "Only show entries whose account or name contains this text")
+
+	fs.Parse(os.Args[2:])
+
+	err := WatchMFA(*filter)
+	if err != nil {
+		fmt.Printf("Error watching MFA codes: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleProvisionMFA() {
+	fs := flag.NewFlagSet("provision-mfa", flag.ExitOnError)
+	account := fs.String("account", "", "Account name (required)")
+	name := fs.String("name", "", "Name/email (required)")
+	issuer := fs.String("issuer", "", "Issuer shown in authenticator apps (default: account)")
+	size := fs.Int("bytes", 20, "Secret size in bytes (default: 20, minimum: 16)")
+	seconds := fs.Int("s", 30, "Time step in seconds (default: 30)")
+	showQR := fs.Bool("qr", false, "Print the otpauth URI as a QR code")
+	force := fs.Bool("force", false, "Replace an existing secret for this account and name")
+
+	fs.Parse(os.Args[2:])
+
+	if *account == "" || *name == "" {
+		fmt.Println("Error: --account and --name are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	err := ProvisionMFA(*account, *name, *issuer, *size, *seconds, *showQR, *force)
+	if err != nil {
+		fmt.Printf("Error provisioning MFA: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleValidateMFA() {
+	fs := flag.NewFlagSet("validate-mfa", flag.ExitOnError)
+	account := fs.String("account", "", "Account name (required)")
+	name := fs.String("name", "", "Name/email (required)")
+	code := fs.String("code", "", "Code submitted by the user (required)")
+	drift := fs.Int("drift", 1, "Periods of clock drift accepted either side (default: 1)")
+
+	fs.Parse(os.Args[2:])
+
+	if *account == "" || *name == "" || *code == "" {
+		fmt.Println("Error: --account, --name, and --code are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ok, err := ValidateMFA(*account, *name, *code, *drift)
+	if err != nil {
+		fmt.Printf("Error validating MFA code: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !ok {
+		fmt.Println("MFA code rejected")
+		os.Exit(1)
+	}
+	fmt.Println("MFA code accepted")
+}
+
+func handleAddRecovery() {
+	fs := flag.NewFlagSet("add-recovery", flag.ExitOnError)
+	account := fs.String("account", "", "Account name (required)")
+	name := fs.String("name", "", "Name/email (required)")
+	codes := fs.String("codes", "", "Comma-separated recovery codes")
+	file := fs.String("file", "", "File with one recovery code per line")
+
+	fs.Parse(os.Args[2:])
+
+	if *account == "" || *name == "" || (*codes == "" && *file == "") {
+		fmt.Println("Error: --account, --name, and --codes or --file are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	recoveryCodes := parseRecoveryCodes(*codes)
+	if *file != "" {
+		fileCodes, err := readRecoveryCodesFile(*file)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		recoveryCodes = append(recoveryCodes, fileCodes...)
+	}
+
+	err := AddRecoveryCodes(*account, *name, recoveryCodes)
+	if err != nil {
+		fmt.Printf("Error adding recovery codes: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleUseRecovery() {
+	fs := flag.NewFlagSet("use-recovery", flag.ExitOnError)
+	account := fs.String("account", "", "Account name (required)")
+	name := fs.String("name", "", "Name/email (required)")
+	code := fs.String("code", "", "Recovery code that was consumed (required)")
+
+	fs.Parse(os.Args[2:])
+
+	if *account == "" || *name == "" || *code == "" {
+		fmt.Println("Error: --account, --name, and --code are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	err := UseRecoveryCode(*account, *name, *code)
+	if err != nil {
+		fmt.Printf("Error using recovery code: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleListRecovery() {
+	fs := flag.NewFlagSet("list-recovery", flag.ExitOnError)
+	account := fs.String("account", "", "Account name (required)")
+	name := fs.String("name", "", "Name/email (required)")
+
+	fs.Parse(os.Args[2:])
+
+	if *account == "" || *name == "" {
+		fmt.Println("Error: --account and --name are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	err := ListRecoveryCodes(*account, *name)
+	if err != nil {
+		fmt.Printf("Error listing recovery codes: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generatorFlags holds the password generator options shared by add-pass
+// and policy add.
+type generatorFlags struct {
+	length       int
+	smallAlpha   bool
+	largeAlpha   bool
+	digits       bool
+	specialChars string
+	noAmbiguous  bool
+	minCounts    string
+	maxCounts    string
+	rules        string
+	minScore     int
+	layout       string
+	typeable     bool
+	shiftToggles int
+
+	passphrase  bool
+	words       int
+	separator   string
+	wordlist    string
+	capitalize  string
+	insertDigit bool
+
+	pronounceable bool
+	syllables     int
+}
+
+func addGeneratorFlags(fs *flag.FlagSet) *generatorFlags {
+	g := &generatorFlags{}
+
+	fs.IntVar(&g.length, "l", 16, "Password length (default: 16)")
+	fs.IntVar(&g.length, "length", 16, "Same as -l")
+	fs.BoolVar(&g.smallAlpha, "a", false, "Include lowercase letters")
+	fs.BoolVar(&g.smallAlpha, "lower", false, "Same as -a")
+	fs.BoolVar(&g.largeAlpha, "A", false, "Include uppercase letters")
+	fs.BoolVar(&g.largeAlpha, "upper", false, "Same as -A")
+	fs.BoolVar(&g.digits, "d", false, "Include digits")
+	fs.BoolVar(&g.digits, "digits", false, "Same as -d")
+	fs.StringVar(&g.specialChars, "s", "", "Special characters to include (use 'default' for common special chars or provide custom)")
+	fs.StringVar(&g.specialChars, "special", "", "Same as -s")
+	fs.BoolVar(&g.noAmbiguous, "no-ambiguous", false, "Leave out easily confused characters ("+ambiguousChars+")")
+	fs.StringVar(&g.minCounts, "min", "", "Minimum characters per class, e.g. digits=2,special=1 (default: 1 per selected class)")
+	fs.StringVar(&g.maxCounts, "max", "", "Maximum characters per class, e.g. special=2")
+	fs.StringVar(&g.rules, "rules", "", "Site requirements in passwordrules format; replaces -a/-A/-d/-s and clamps -l")
+	fs.StringVar(&g.layout, "layout", "", "Keyboard layouts the password is typed on, e.g. us or de,fr: "+strings.Join(layoutNames(), ", "))
+	fs.BoolVar(&g.typeable, "typeable", false, "Only use characters typeable without dead keys or AltGr on --layout (default: us)")
+	fs.IntVar(&g.shiftToggles, "max-shift-toggles", 0, "Limit how often Shift is pressed or released while typing on the first --layout")
+	fs.IntVar(&g.minScore, "min-score", 0, "Regenerate until the estimated strength score (0-4) is at least this")
+
+	fs.BoolVar(&g.passphrase, "passphrase", false, "Generate a diceware-style passphrase instead of a random string")
+	fs.IntVar(&g.words, "words", 6, "Passphrase word count (default: 6)")
+	fs.StringVar(&g.separator, "separator", "-", "Passphrase word separator (default: -)")
+	fs.StringVar(&g.wordlist, "wordlist", "eff-large", "Passphrase wordlist: "+strings.Join(wordlistNames(), ", "))
+	fs.StringVar(&g.capitalize, "capitalize", "none", "Passphrase or pronounceable capitalization: none, first, upper or random")
+	fs.BoolVar(&g.insertDigit, "digit", false, "Insert a random digit into the passphrase or pronounceable password")
+
+	fs.BoolVar(&g.pronounceable, "pronounceable", false, "Generate a pronounceable password from random syllables")
+	fs.IntVar(&g.syllables, "syllables", 6, "Pronounceable syllable count (default: 6)")
+
+	return g
+}
+
+// generatorFlagNames lists every flag registered by addGeneratorFlags, so
+// callers can tell whether any generator option was given explicitly.
+var generatorFlagNames = []string{
+	"l", "length", "a", "lower", "A", "upper", "d", "digits", "s", "special",
+	"no-ambiguous", "min", "max", "rules", "min-score", "layout", "typeable", "max-shift-toggles",
+	"passphrase", "words", "separator", "wordlist", "capitalize", "digit",
+	"pronounceable", "syllables",
+}
+
+func generatorFlagsSet(fs *flag.FlagSet) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range generatorFlagNames {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+	return set
+}
+
+// policy builds the generator settings selected on the command line.
+func (g *generatorFlags) policy() (GeneratorPolicy, error) {
+	if g.passphrase {
+		return GeneratorPolicy{Passphrase: &PassphraseOptions{
+			Words:      g.words,
+			Separator:  g.separator,
+			Wordlist:   g.wordlist,
+			Capitalize: g.capitalize,
+			Digit:      g.insertDigit,
+		}, MinScore: g.minScore}, nil
+	}
+
+	if g.pronounceable {
+		return GeneratorPolicy{Pronounceable: &PronounceableOptions{
+			Syllables:  g.syllables,
+			Capitalize: g.capitalize,
+			Digit:      g.insertDigit,
+		}, MinScore: g.minScore}, nil
+	}
+
+	layouts, err := parseLayouts(g.layout)
+	if err != nil {
+		return GeneratorPolicy{}, fmt.Errorf("invalid --layout: %v", err)
+	}
+
+	if g.rules != "" {
+		opts, err := optionsFromRules(g.rules, g.length)
+		if err != nil {
+			return GeneratorPolicy{}, fmt.Errorf("invalid --rules: %v", err)
+		}
+		opts.NoAmbiguous = g.noAmbiguous
+		opts.Layouts, opts.Typeable, opts.MaxShiftToggles = layouts, g.typeable, g.shiftToggles
+		return GeneratorPolicy{Password: &opts, MinScore: g.minScore}, nil
+	}
+
+	// Handle special characters logic - THIS IS THE FIX
+	actualSpecialChars := g.specialChars
+	if g.specialChars == "default" {
+		actualSpecialChars = defaultSpecialChars
+	}
+
+	smallAlpha, largeAlpha, digits := g.smallAlpha, g.largeAlpha, g.digits
+
+	// If no character type flags are specified at all, use all character types with default special chars
+	if !smallAlpha && !largeAlpha && !digits && g.specialChars == "" {
+		smallAlpha = true
+		largeAlpha = true
+		digits = true
+		actualSpecialChars = defaultSpecialChars
+	}
+
+	mins, err := parseClassLimits(g.minCounts)
+	if err != nil {
+		return GeneratorPolicy{}, fmt.Errorf("invalid --min: %v", err)
+	}
+	maxs, err := parseClassLimits(g.maxCounts)
+	if err != nil {
+		return GeneratorPolicy{}, fmt.Errorf("invalid --max: %v", err)
+	}
+	for name, max := range maxs {
+		if max == 0 {
+			return GeneratorPolicy{}, fmt.Errorf("invalid --max: %s=0 would exclude the class; leave it unselected instead", name)
+		}
+	}
+
+	return GeneratorPolicy{Password: &PasswordOptions{
+		Length:       g.length,
+		SmallAlpha:   smallAlpha,
+		LargeAlpha:   largeAlpha,
+		Digits:       digits,
+		SpecialChars: actualSpecialChars,
+		NoAmbiguous:  g.noAmbiguous,
+		Limits:       buildClassLimits(mins, maxs),
+
+		Layouts:         layouts,
+		Typeable:        g.typeable,
+		MaxShiftToggles: g.shiftToggles,
+	}, MinScore: g.minScore}, nil
 }
 
 func handleAddPassword() {
 	fs := flag.NewFlagSet("add-pass", flag.ExitOnError)
-	length := fs.Int("l", 16, "Password length (default: 16)")
-	smallAlpha := fs.Bool("a", false, "Include lowercase letters")
-	largeAlpha := fs.Bool("A", false, "Include uppercase letters")
-	digits := fs.Bool("d", false, "Include digits")
-	specialChars := fs.String("s", "", "Special characters to include (use 'default' for common special chars or provide custom)")
 	name := fs.String("name", "", "Name/service (required)")
 	account := fs.String("account", "", "Account/username (required)")
+	policyName := fs.String("policy", "", "Named generator policy to use (see 'policy add')")
+	generator := addGeneratorFlags(fs)
 
 	fs.Parse(os.Args[2:])
 
@@ -121,29 +516,464 @@ func handleAddPassword() {
 		os.Exit(1)
 	}
 
-	// Handle special characters logic - THIS IS THE FIX
-	actualSpecialChars := *specialChars
-	if *specialChars == "default" {
-		actualSpecialChars = "!@#$%^&*()_+-=[]{}|;:,.<>?"
+	var policy GeneratorPolicy
+	var err error
+	usePolicy := *policyName != ""
+	if usePolicy && generatorFlagsSet(fs) {
+		fmt.Println("Error: --policy cannot be combined with generator flags; add a new policy instead")
+		os.Exit(1)
 	}
-	
-	// If no character type flags are specified at all, use all character types with default special chars
-	if !*smallAlpha && !*largeAlpha && !*digits && *specialChars == "" {
-		*smallAlpha = true
-		*largeAlpha = true  
-		*digits = true
-		actualSpecialChars = "!@#$%^&*()_+-=[]{}|;:,.<>?"
+
+	// Without explicit generator flags the default policy, if any, applies.
+	if !usePolicy && !generatorFlagsSet(fs) {
+		policy, usePolicy, err = GetPolicy("")
+		if err != nil {
+			fmt.Printf("Error loading default policy: %v\n", err)
+			os.Exit(1)
+		}
+	} else if usePolicy {
+		policy, _, err = GetPolicy(*policyName)
+		if err != nil {
+			fmt.Printf("Error loading policy: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if !usePolicy {
+		policy, err = generator.policy()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	err := AddPassword(*name, *account, *length, *smallAlpha, *largeAlpha, *digits, actualSpecialChars)
+	err = AddPasswordWithPolicy(*name, *account, policy)
 	if err != nil {
 		fmt.Printf("Error generating password: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+func handlePolicy() {
+	if len(os.Args) < 3 {
+		fmt.Println("Error: policy requires a subcommand: add, list, remove or default")
+		printUsage()
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "add":
+		handlePolicyAdd()
+	case "list":
+		err := ListPolicies()
+		if err != nil {
+			fmt.Printf("Error listing policies: %v\n", err)
+			os.Exit(1)
+		}
+	case "remove":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: policy remove requires a policy name")
+			os.Exit(1)
+		}
+		err := RemovePolicy(os.Args[3])
+		if err != nil {
+			fmt.Printf("Error removing policy: %v\n", err)
+			os.Exit(1)
+		}
+	case "default":
+		name := ""
+		if len(os.Args) > 3 {
+			name = os.Args[3]
+		}
+		err := SetDefaultPolicy(name)
+		if err != nil {
+			fmt.Printf("Error setting default policy: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Unknown policy subcommand: %s\n", os.Args[2])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func handlePolicyAdd() {
+	if len(os.Args) < 4 || strings.HasPrefix(os.Args[3], "-") {
+		fmt.Println("Error: policy add requires a policy name")
+		os.Exit(1)
+	}
+	name := os.Args[3]
+
+	fs := flag.NewFlagSet("policy add", flag.ExitOnError)
+	generator := addGeneratorFlags(fs)
+
+	fs.Parse(os.Args[4:])
+
+	policy, err := generator.policy()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	policy.Name = name
+
+	err = AddPolicy(policy)
+	if err != nil {
+		fmt.Printf("Error adding policy: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleSpectre() {
+	if len(os.Args) < 3 {
+		fmt.Println("Error: spectre requires a subcommand: add, get, list or remove")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("spectre "+os.Args[2], flag.ExitOnError)
+	site := fs.String("site", "", "Site name, e.g. example.com")
+	login := fs.String("login", "", "Login/username for the site")
+
+	switch os.Args[2] {
+	case "add":
+		fullName := fs.String("full-name", "", "Your full name; part of every derived password (stored)")
+		counter := fs.Uint("counter", 1, "Site counter; increase to change the password")
+		template := fs.String("template", spectreDefaultTemplate, "Password template: "+strings.Join(spectreTemplateNames(), ", "))
+		fs.Parse(os.Args[3:])
+
+		err := AddSpectreSite(*fullName, SpectreSite{
+			Site:     *site,
+			Login:    *login,
+			Counter:  uint32(*counter),
+			Template: *template,
+		})
+		if err != nil {
+			fmt.Printf("Error adding spectre site: %v\n", err)
+			os.Exit(1)
+		}
+	case "get":
+		fs.Parse(os.Args[3:])
+		if *site == "" {
+			fmt.Println("Error: --site is required")
+			os.Exit(1)
+		}
+		err := GetSpectrePassword(*site, *login)
+		if err != nil {
+			fmt.Printf("Error getting spectre password: %v\n", err)
+			os.Exit(1)
+		}
+	case "remove":
+		fs.Parse(os.Args[3:])
+		if *site == "" {
+			fmt.Println("Error: --site is required")
+			os.Exit(1)
+		}
+		err := RemoveSpectreSite(*site, *login)
+		if err != nil {
+			fmt.Printf("Error removing spectre site: %v\n", err)
+			os.Exit(1)
+		}
+	case "list":
+		err := ListSpectreSites()
+		if err != nil {
+			fmt.Printf("Error listing spectre sites: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Unknown spectre subcommand: %s\n", os.Args[2])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func handleGen() {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	kind := fs.String("kind", secretKindHex, "Secret kind: "+strings.Join(secretKinds, ", "))
+	bits := fs.Int("bits", defaultSecretBits, "Random bits, a multiple of 8 (ignored for uuid)")
+	name := fs.String("name", "", "Save as a password entry under this name/service")
+	account := fs.String("account", "", "Account/username for the saved entry")
+
+	fs.Parse(os.Args[2:])
+
+	opts := SecretOptions{Kind: *kind, Bits: *bits}
+	if *kind == secretKindUUID {
+		opts.Bits = 0
+	}
+
+	if *name == "" && *account == "" {
+		// Print only the value so it can be captured by scripts.
+		secret, err := generateSecret(opts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(secret)
+		return
+	}
+
+	if *name == "" || *account == "" {
+		fmt.Println("Error: --name and --account must be given together")
+		os.Exit(1)
+	}
+
+	err := AddPasswordWithPolicy(*name, *account, GeneratorPolicy{Secret: &opts})
+	if err != nil {
+		fmt.Printf("Error saving secret: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleAudit() {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	maxAge := fs.String("max-age", "365d", "Flag passwords not changed for longer than this, e.g. 180d")
+	minScore := fs.Int("min-score", 3, "Flag passwords with a lower strength score (0-4)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	failOn := fs.String("fail-on", severityLow, "Lowest severity that makes the exit status 2: high, medium or low")
+
+	fs.Parse(os.Args[2:])
+
+	age, err := parseAge(*maxAge)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	severity, err := parseSeverity(*failOn)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	findings, err := AuditVault(AuditOptions{MaxAge: age, MinScore: *minScore, FailOn: severity}, *asJSON)
+	if err != nil {
+		fmt.Printf("Error running audit: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Exit status 2 lets scripts tell findings apart from errors.
+	if findings > 0 {
+		os.Exit(2)
+	}
+}
+
+func handleBreachCheck() {
+	fs := flag.NewFlagSet("breach-check", flag.ExitOnError)
+	file := fs.String("file", "", "HIBP SHA-1 hash file, ordered by hash (required)")
+	name := fs.String("name", "", "Only check this name/service")
+	account := fs.String("account", "", "Only check this account/username")
+
+	fs.Parse(os.Args[2:])
+
+	if *file == "" {
+		fmt.Println("Error: --file is required")
+		os.Exit(1)
+	}
+	if (*name == "") != (*account == "") {
+		fmt.Println("Error: --name and --account must be given together")
+		os.Exit(1)
+	}
+
+	breached, err := CheckBreaches(*file, *name, *account)
+	if err != nil {
+		fmt.Printf("Error checking breaches: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Same convention as audit: 2 means breached passwords were found.
+	if breached > 0 {
+		os.Exit(2)
+	}
+}
+
+func handleImport() {
+	if len(os.Args) < 4 || strings.HasPrefix(os.Args[3], "-") {
+		fmt.Println("Error: import requires a format and a file, e.g. import bitwarden export.json")
+		os.Exit(1)
+	}
+	format, path := os.Args[2], os.Args[3]
+
+	fs := flag.NewFlagSet("import "+format, flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Show what would be created or conflict without saving")
+	csvFormat := fs.String("format", "generic", "CSV layout: "+strings.Join(csvFormatNames(), ", "))
+	mapping := fs.String("map", "", "Generic CSV column mapping, e.g. name=Title,account=Login,password=Pass")
+
+	fs.Parse(os.Args[4:])
+
+	if format != "csv" {
+		var csvFlags []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "format" || f.Name == "map" {
+				csvFlags = append(csvFlags, "--"+f.Name)
+			}
+		})
+		if len(csvFlags) > 0 {
+			fmt.Printf("Error: %s can only be used with csv imports\n", strings.Join(csvFlags, " and "))
+			os.Exit(1)
+		}
+	}
+
+	var err error
+	switch format {
+	case "csv":
+		err = ImportCSV(path, *csvFormat, *mapping, *dryRun)
+	case "bitwarden":
+		err = ImportBitwarden(path, *dryRun)
+	case "keepass", "kdbx":
+		err = ImportKeePass(path, *dryRun)
+	case "pass":
+		err = ImportPassStore(path, *dryRun)
+	default:
+		fmt.Printf("Unknown import format: %s\n", format)
+		printUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error importing: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleExport() {
+	if len(os.Args) < 4 || strings.HasPrefix(os.Args[3], "-") {
+		fmt.Println("Error: export requires a format and a file, e.g. export kdbx passwords.kdbx")
+		os.Exit(1)
+	}
+	format, path := os.Args[2], os.Args[3]
+
+	fs := flag.NewFlagSet("export "+format, flag.ExitOnError)
+	cipherName := fs.String("cipher", "aes", "Database cipher: aes or chacha20")
+	gpgID := fs.String("gpg-id", "", "GPG key to initialize a new password store for (pass export)")
+
+	fs.Parse(os.Args[4:])
+
+	var err error
+	switch format {
+	case "keepass", "kdbx":
+		err = ExportKeePass(path, *cipherName)
+	case "pass":
+		err = ExportPassStore(path, *gpgID)
+	default:
+		fmt.Printf("Unknown export format: %s\n", format)
+		printUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error exporting: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleShare() {
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	to := fs.String("to", "", "Recipient age public key(s), comma-separated (required)")
+	name := fs.String("name", "", "Service name (required)")
+	account := fs.String("account", "", "Account/username (default: every account of the service)")
+	out := fs.String("o", "bundle.age", "Bundle file to write")
+
+	fs.Parse(os.Args[2:])
+
+	if *to == "" || *name == "" {
+		fmt.Println("Error: --to and --name are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if err := ShareEntries(*name, *account, *to, *out); err != nil {
+		fmt.Printf("Error sharing entries: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleReceive() {
+	if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+		fmt.Println("Error: receive requires a bundle file, e.g. receive bundle.age -i key.txt")
+		os.Exit(1)
+	}
+	path := os.Args[2]
+
+	fs := flag.NewFlagSet("receive", flag.ExitOnError)
+	identity := fs.String("i", "", "age identity file, as written by age-keygen (required)")
+	dryRun := fs.Bool("dry-run", false, "Show what would be created or conflict without saving")
+
+	fs.Parse(os.Args[3:])
+
+	if *identity == "" {
+		fmt.Println("Error: -i is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if err := ReceiveBundle(path, *identity, *dryRun); err != nil {
+		fmt.Printf("Error receiving bundle: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleRotate() {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	name := fs.String("name", "", "Name/service to rotate")
+	account := fs.String("account", "", "Account/username to rotate")
+	olderThan := fs.String("older-than", "", "Rotate every password last changed before this age, e.g. 90d")
+	confirm := fs.Bool("confirm", false, "Make pending passwords current")
+	discard := fs.Bool("discard", false, "Drop pending passwords")
+	force := fs.Bool("force", false, "Replace passwords that are already pending")
+
+	fs.Parse(os.Args[2:])
+
+	if (*name == "") != (*account == "") {
+		fmt.Println("Error: --name and --account must be used together")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *confirm || *discard {
+		if *confirm && *discard {
+			fmt.Println("Error: --confirm and --discard cannot be used together")
+			os.Exit(1)
+		}
+		err := FinishRotation(*name, *account, *confirm)
+		if err != nil {
+			fmt.Printf("Error finishing rotation: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if (*name == "") == (*olderThan == "") {
+		fmt.Println("Error: either --name and --account, or --older-than is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var age time.Duration
+	if *olderThan != "" {
+		var err error
+		age, err = parseAge(*olderThan)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	err := RotatePasswords(*name, *account, age, *force)
+	if err != nil {
+		fmt.Printf("Error rotating passwords: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func handleGetPasswords() {
-	err := GetPasswords()
+	fs := flag.NewFlagSet("get-pass", flag.ExitOnError)
+	name := fs.String("name", "", "Only show passwords for this name/service")
+	account := fs.String("account", "", "Only show passwords for this account")
+	display := fs.String("display", displayPlain, "How to show passwords: "+strings.Join(displayModes, ", "))
+
+	fs.Parse(os.Args[2:])
+
+	if err := validateDisplayMode(*display); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	err := GetPasswords(*name, *account, *display)
 	if err != nil {
 		fmt.Printf("Error retrieving passwords: %v\n", err)
 		os.Exit(1)
@@ -152,9 +982,13 @@ func handleGetPasswords() {
 
 func handleAddMPIN() {
 	fs := flag.NewFlagSet("add-mpin", flag.ExitOnError)
-	length := fs.Int("l", 4, "MPIN length (default: 4)")
+	length := fs.Int("l", 0, "MPIN length (default: 4, or the kind's usual length)")
 	name := fs.String("name", "", "Name/service (required)")
 	account := fs.String("account", "", "Account/username (required)")
+	kind := fs.String("kind", "", "PIN kind: "+strings.Join(mpinKindOrder[:len(mpinKindOrder)-1], ", "))
+	set := fs.Bool("set", false, "Enter an issued PIN at a hidden prompt instead of generating one")
+	allow := fs.String("allow", "", "Weak-PIN checks to skip: "+strings.Join(pinPolicyChecks, ", ")+" or all")
+	strict := fs.Bool("strict", false, "Enforce every weak-PIN check again")
 
 	fs.Parse(os.Args[2:])
 
@@ -164,7 +998,36 @@ func handleAddMPIN() {
 		os.Exit(1)
 	}
 
-	err := AddMPIN(*name, *account, *length)
+	var policy *PINPolicy
+	if *allow != "" || *strict {
+		parsed, err := parsePINPolicy(*allow, *strict)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		policy = &parsed
+	}
+
+	if *set {
+		pin, ok, err := promptConfirmedSecret("PIN: ", "Repeat PIN: ")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Println("Error: PINs do not match")
+			os.Exit(1)
+		}
+
+		err = SetMPIN(*name, *account, *kind, pin, policy)
+		if err != nil {
+			fmt.Printf("Error storing MPIN: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	err := AddMPIN(*name, *account, *kind, *length, policy)
 	if err != nil {
 		fmt.Printf("Error adding MPIN: %v\n", err)
 		os.Exit(1)
@@ -201,40 +1064,197 @@ func handleListMPINs() {
 
 func printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  ./main setup-mfa --account <account> --name <name> -k <secret_key> [-s <seconds>]")
+	fmt.Println("  ./main setup-mfa --account <account> --name <name> -k <secret_key> [-s <seconds>] [--type totp|steam]")
 	fmt.Println("  ./main list")
-	fmt.Println("  ./main generate --account <account> --name <name>")
-	fmt.Println("  ./main add-pass --name <service> --account <username> [-l <length>] [-a] [-A] [-d] [-s <special_chars>]")
-	fmt.Println("  ./main get-pass")
-	fmt.Println("  ./main add-mpin --name <service> --account <username> [-l <length>]")
+	fmt.Println("  ./main generate --account <account> --name <name> [--next] [--min-remaining <seconds>] [--at <RFC3339>]")
+	fmt.Println("  ./main watch [--filter <text>]")
+	fmt.Println("  ./main provision-mfa --account <account> --name <name> [--issuer <issuer>] [--bytes <size>] [-s <seconds>] [--qr] [--force]")
+	fmt.Println("  ./main validate-mfa --account <account> --name <name> --code <code> [--drift <periods>]")
+	fmt.Println("  ./main add-recovery --account <account> --name <name> [--codes <code,code,...>] [--file <path>]")
+	fmt.Println("  ./main use-recovery --account <account> --name <name> --code <code>")
+	fmt.Println("  ./main list-recovery --account <account> --name <name>")
+	fmt.Println("  ./main add-pass --name <service> --account <username> [-l <length>] [-a] [-A] [-d] [-s <special_chars>] [--no-ambiguous] [--min <class=n,...>] [--max <class=n,...>]")
+	fmt.Println("  ./main add-pass --name <service> --account <username> --rules <passwordrules> [-l <length>]")
+	fmt.Println("  ./main add-pass --name <service> --account <username> --policy <policy>")
+	fmt.Println("  ./main rotate (--name <service> --account <username> | --older-than <age>) [--force]")
+	fmt.Println("  ./main rotate --confirm|--discard [--name <service> --account <username>]")
+	fmt.Println("  ./main spectre add --site <site> [--login <username>] [--counter <n>] [--template <template>] [--full-name <name>]")
+	fmt.Println("  ./main spectre get --site <site> [--login <username>]")
+	fmt.Println("  ./main spectre list")
+	fmt.Println("  ./main spectre remove --site <site> [--login <username>]")
+	fmt.Println("  ./main audit [--max-age <age>] [--min-score <0-4>] [--fail-on <severity>] [--json]")
+	fmt.Println("  ./main breach-check --file <hibp-sha1-ordered.txt> [--name <service> --account <username>]")
+	fmt.Println("  ./main import bitwarden <export.json> [--dry-run]")
+	fmt.Println("  ./main import kdbx <database.kdbx> [--dry-run]")
+	fmt.Println("  ./main import csv <export.csv> [--format chrome|firefox|lastpass|1password|generic] [--map field=Column,...] [--dry-run]")
+	fmt.Println("  ./main export kdbx <database.kdbx> [--cipher aes|chacha20]")
+	fmt.Println("  ./main import pass <password-store dir> [--dry-run]")
+	fmt.Println("  ./main export pass <password-store dir> [--gpg-id KEY]")
+	fmt.Println("  ./main share --to <age1...> --name <service> [--account <username>] [-o <bundle.age>]")
+	fmt.Println("  ./main receive <bundle.age> -i <identity file> [--dry-run]")
+	fmt.Println("  ./main gen [--kind hex|base64url|uuid|bytes] [--bits <n>] [--name <service> --account <username>]")
+	fmt.Println("  ./main policy add <policy> [add-pass generator flags]")
+	fmt.Println("  ./main policy list")
+	fmt.Println("  ./main policy remove <policy>")
+	fmt.Println("  ./main policy default [<policy>]")
+	fmt.Println("  ./main add-pass --name <service> --account <username> --passphrase [--words <n>] [--separator <sep>] [--wordlist <list>] [--capitalize <mode>] [--digit]")
+	fmt.Println("  ./main add-pass --name <service> --account <username> --pronounceable [--syllables <n>] [--capitalize <mode>] [--digit]")
+	fmt.Println("  ./main get-pass [--name <service>] [--account <username>] [--display plain|chunked|nato|big]")
+	fmt.Println("  ./main add-mpin --name <service> --account <username> [--kind <kind>] [-l <length>] [--allow <checks>] [--strict]")
+	fmt.Println("  ./main add-mpin --name <service> --account <username> --set [--kind <kind>]")
 	fmt.Println("  ./main get-mpin --name <service> --account <username>")
 	fmt.Println("  ./main list-mpin")
 	fmt.Println()
 	fmt.Println("MFA Examples:")
 	fmt.Println("  ./main setup-mfa --account google --name dummy@gmail.com -k \"rfg3 oi7l zdiy 2yha sypa gdm6 g3qa d3pc\" -s 30")
+	fmt.Println("  ./main setup-mfa --account steam --name gamer -k \"<shared_secret>\" --type steam")
 	fmt.Println("  ./main list")
 	fmt.Println("  ./main generate --account google --name dummy@gmail.com")
+	fmt.Println("  ./main generate --account google --name dummy@gmail.com --next --min-remaining 5")
+	fmt.Println("  ./main generate --account google --name dummy@gmail.com --at 2024-01-01T12:00:00Z")
+	fmt.Println("  ./main watch --filter google")
+	fmt.Println("  ./main provision-mfa --account myservice --name user@example.com --issuer MyService --qr")
+	fmt.Println("  ./main validate-mfa --account myservice --name user@example.com --code 123456")
+	fmt.Println("  ./main add-recovery --account google --name dummy@gmail.com --codes \"1234-5678,8765-4321\"")
+	fmt.Println("  ./main use-recovery --account google --name dummy@gmail.com --code 1234-5678")
 	fmt.Println()
 	fmt.Println("Password Examples:")
 	fmt.Println("  ./main add-pass --name google --account dummy@gmail.com -l 20 -a -A -d -s default")
 	fmt.Println("  ./main add-pass --name github --account myuser -l 16 -s \"!@#$\"")
 	fmt.Println("  ./main add-pass --name twitter --account handle -a -A -d")
+	fmt.Println("  ./main add-pass --name bank --account me -l 12 -a -A -d -s default --no-ambiguous --max special=2")
+	fmt.Println("  ./main add-pass --name shop --account me --rules \"minlength: 12; maxlength: 20; required: lower; required: upper; required: digit; allowed: [-_!];\"")
+	fmt.Println("  ./main add-pass --name kvm --account root -l 16 --layout us,de --typeable --max-shift-toggles 4")
+	fmt.Println("  ./main add-pass --name tv --account me --passphrase --words 5 --wordlist eff-short --capitalize first --digit")
+	fmt.Println("  ./main add-pass --name wifi --account guest --pronounceable --syllables 7 --capitalize random")
+	fmt.Println("  ./main gen --kind base64url --bits 256")
+	fmt.Println("  ./main gen --kind hex --bits 512 --name jwt-signing --account api")
+	fmt.Println("  ./main policy add bank --length 12 --digits --upper")
+	fmt.Println("  ./main policy add strong --length 20 --min-score 4")
+	fmt.Println("  ./main policy default bank")
+	fmt.Println("  ./main add-pass --name mybank --account me --policy bank")
+	fmt.Println("  ./main rotate --name google --account dummy@gmail.com")
+	fmt.Println("  ./main rotate --older-than 90d")
+	fmt.Println("  ./main rotate --confirm")
+	fmt.Println("  ./main spectre add --site github.com --login octocat --full-name \"Robert Lee Mitchell\"")
+	fmt.Println("  ./main spectre get --site github.com")
+	fmt.Println("  ./main audit --max-age 180d --json")
+	fmt.Println("  ./main breach-check --file pwned-passwords-sha1-ordered-by-hash-v8.txt")
+	fmt.Println("  ./main import bitwarden bitwarden_export.json --dry-run")
+	fmt.Println("  ./main import kdbx Passwords.kdbx")
+	fmt.Println("  ./main import csv 'Chrome Passwords.csv' --format chrome")
+	fmt.Println("  ./main import csv sheet.csv --map name=Site,account=Login,password=Secret,notes=Comment")
+	fmt.Println("  ./main export kdbx backup.kdbx --cipher chacha20")
+	fmt.Println("  ./main import pass ~/.password-store --dry-run")
+	fmt.Println("  ./main export pass ~/.password-store --gpg-id ops@example.com")
+	fmt.Println("  ./main share --to age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p --name github -o github.age")
+	fmt.Println("  ./main receive github.age -i ~/.config/age/key.txt --dry-run")
 	fmt.Println("  ./main get-pass")
+	fmt.Println("  ./main get-pass --name wifi --display nato")
 	fmt.Println()
 	fmt.Println("MPIN Examples:")
 	fmt.Println("  ./main add-mpin --name google --account dummy@gmail.com -l 4")
 	fmt.Println("  ./main add-mpin --name bank --account myaccount -l 6")
+	fmt.Println("  ./main add-mpin --name alarm --account home --kind alarm --allow dates,reuse")
+	fmt.Println("  ./main add-mpin --name visa --account 1234 --kind card --set")
 	fmt.Println("  ./main get-mpin --name google --account dummy@gmail.com")
 	fmt.Println("  ./main list-mpin")
 	fmt.Println()
 	fmt.Println("Password Flags:")
-	fmt.Println("  -l: Password length (default: 16)")
-	fmt.Println("  -a: Include lowercase letters")
-	fmt.Println("  -A: Include uppercase letters") 
-	fmt.Println("  -d: Include digits")
-	fmt.Println("  -s: Special characters - use 'default' for common special chars or provide custom like \"!@#$\"")
+	fmt.Println("  -l, --length: Password length (default: 16)")
+	fmt.Println("  -a, --lower: Include lowercase letters")
+	fmt.Println("  -A, --upper: Include uppercase letters") 
+	fmt.Println("  -d, --digits: Include digits")
+	fmt.Println("  -s, --special: Special characters - use 'default' for common special chars or provide custom like \"!@#$\"")
 	fmt.Println("      If not specified with other flags, no special characters will be used")
+	fmt.Println("  --no-ambiguous: Leave out easily confused characters (" + ambiguousChars + ")")
+	fmt.Println("  --min/--max: Per-class counts for lower, upper, digits, special, e.g. --min digits=2 --max special=2")
+	fmt.Println("      Every selected class appears at least once unless its minimum is set to 0")
+	fmt.Println("      Limits apply to selected classes only; --max must be at least 1 (leave a class out to exclude it)")
+	fmt.Println("  --rules: passwordrules string (minlength, maxlength, required, allowed, max-consecutive)")
+	fmt.Println("  --layout: Keyboard layouts the password is typed on: " + strings.Join(layoutNames(), ", ") + ", comma-separated")
+	fmt.Println("  --typeable: Drop characters that need dead keys or AltGr on any --layout (default layout: us)")
+	fmt.Println("  --max-shift-toggles: Limit Shift presses and releases while typing on the first layout")
+	fmt.Println("  --min-score: Regenerate until the strength estimate reaches this score (0-4)")
+	fmt.Println("  --policy: Use a named policy (not with generator flags); without any generator")
+	fmt.Println("            flags the default policy applies")
+	fmt.Println()
+	fmt.Println("Passphrase Flags:")
+	fmt.Println("  --passphrase: Generate words instead of random characters")
+	fmt.Println("  --words: Number of words (default: 6)")
+	fmt.Println("  --separator: Text placed between words (default: -)")
+	fmt.Println("  --wordlist: " + strings.Join(wordlistNames(), ", ") + " (default: eff-large)")
+	fmt.Println("  --capitalize: none, first, upper or random (default: none)")
+	fmt.Println("  --digit: Insert one random digit")
+	fmt.Println()
+	fmt.Println("Pronounceable Flags:")
+	fmt.Println("  --pronounceable: Build the password from random consonant-vowel syllables")
+	fmt.Println("  --syllables: Number of syllables (default: 6, about 53 bits)")
+	fmt.Println("  --capitalize: none, first, upper or random per syllable (random adds 1 bit each)")
+	fmt.Println("  --digit: Append one random digit")
+	fmt.Println()
+	fmt.Println("Display Flags (get-pass):")
+	fmt.Println("  --display: plain, chunked (groups of 4), nato (spelled out with case) or big (ASCII art)")
+	fmt.Println()
+	fmt.Println("Gen Flags:")
+	fmt.Println("  --kind: hex, base64url (unpadded), uuid (version 4) or bytes (standard base64)")
+	fmt.Println("  --bits: Random bits, a multiple of 8 (default: 256)")
+	fmt.Println("  --name/--account: Save the secret as a password entry instead of only printing it")
+	fmt.Println()
+	fmt.Println("Spectre Flags:")
+	fmt.Println("  Passwords are derived from your full name, master password, site and counter")
+	fmt.Println("  with the Spectre (Master Password) algorithm; only the non-secret parameters are stored")
+	fmt.Println("  --template: " + strings.Join(spectreTemplateNames(), ", ") + " (default: long)")
+	fmt.Println("  --counter: Increase to get a new password for the same site (default: 1)")
+	fmt.Println()
+	fmt.Println("Audit Flags:")
+	fmt.Println("  --max-age: Flag passwords older than this (default: 365d)")
+	fmt.Println("  --min-score: Flag passwords scoring below this (default: 3)")
+	fmt.Println("  --json: Print the report as JSON")
+	fmt.Println("  --fail-on: Lowest severity counted for the exit status: high, medium or low (default: low)")
+	fmt.Println("  Passwords with no change date are listed separately instead of flagged as old")
+	fmt.Println("  Exit status is 0 with no counted issues, 2 if any were found and 1 on errors")
+	fmt.Println()
+	fmt.Println("Import Flags:")
+	fmt.Println("  Login items become password entries (notes, custom fields and URIs are kept)")
+	fmt.Println("  and their TOTP secrets become MFA entries. Password-protected exports ask for")
+	fmt.Println("  the export password. Entries already stored are reported as conflicts and kept.")
+	fmt.Println("  KeePass (KDBX 4) groups are kept as the entry's group, entries in the PINs")
+	fmt.Println("  group become MPINs and the recycle bin is ignored.")
+	fmt.Println("  --dry-run: Only show what would be created, conflict or be skipped")
+	fmt.Println("  --format: CSV layout: chrome, firefox, lastpass, 1password or generic (default)")
+	fmt.Println("  --map: Generic CSV columns as field=Column pairs; fields are name, account,")
+	fmt.Println("         password, url, notes, totp, group, updated and archived. Unmapped fields")
+	fmt.Println("         use columns with the same name. Rows without a name fall back to the URL")
+	fmt.Println("         host; skipped rows are reported with their row number. A mapped column")
+	fmt.Println("         missing from the header is an error. --format and --map are csv only.")
+	fmt.Println()
+	fmt.Println("Export Flags:")
+	fmt.Println("  Writes a new KDBX 4 database (Argon2id) readable by KeePass and KeePassXC.")
+	fmt.Println("  TOTP secrets go in the otp field of the matching password; other MFA entries")
+	fmt.Println("  and MPINs go in the MFA and PINs groups.")
+	fmt.Println("  --cipher: aes (default) or chacha20")
+	fmt.Println("  pass: writes group/name/account.gpg files with the password on the first line,")
+	fmt.Println("  url: lines, notes and an otpauth:// line, encrypted with gpg to the store's")
+	fmt.Println("  .gpg-id. Existing files are kept. Importing reads the same layout back.")
+	fmt.Println("  --gpg-id: GPG key to initialize a store that has no .gpg-id yet")
+	fmt.Println()
+	fmt.Println("Share Flags:")
+	fmt.Println("  share writes the matching password, MFA and MPIN entries to an age-encrypted")
+	fmt.Println("  bundle; receive decrypts it and merges it like an import, keeping entries")
+	fmt.Println("  already stored and reporting them as conflicts.")
+	fmt.Println("  --to: Recipient X25519 public keys (age1...), comma-separated")
+	fmt.Println("  --account: Only share this account (default: every account of the service)")
+	fmt.Println("  -o: Bundle file to write (default: bundle.age)")
+	fmt.Println("  -i: Identity file holding the recipient's AGE-SECRET-KEY (see age-keygen)")
 	fmt.Println()
 	fmt.Println("MPIN Flags:")
-	fmt.Println("  -l: MPIN length (default: 4)")
+	fmt.Println("  -l: MPIN length (default: 4, or the kind's usual length)")
+	fmt.Println("  --kind: card (4-12 digits), sim (4-8), puk (8), alarm (4-8), app (4-16; --set also accepts letters)")
+	fmt.Println("  Generated PINs are always digits")
+	fmt.Println("  --set: Type in an issued PIN at a hidden prompt instead of generating one")
+	fmt.Println("  PINs with repeated digits, sequences, common PINs, dates or years, or a PIN")
+	fmt.Println("  used by another entry are regenerated. The policy is stored per entry.")
+	fmt.Println("  --allow: Skip checks for this entry: " + strings.Join(pinPolicyChecks, ", ") + " or all")
+	fmt.Println("  --strict: Enforce every check again (not with --allow)")
 }
@@ -12,13 +12,23 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/cazzano/password_manager_cli/otp"
+)
+
+const (
+	mfaTypeTOTP  = "totp"
+	mfaTypeSteam = "steam"
 )
 
 type MFAEntry struct {
-	Account string `json:"account"`
-	Name    string `json:"name"`
-	Secret  string `json:"secret"`
-	Period  int    `json:"period"`
+	Account       string         `json:"account"`
+	Name          string         `json:"name"`
+	Secret        string         `json:"secret"`
+	Period        int            `json:"period"`
+	Type          string         `json:"type,omitempty"` // Empty means standard TOTP
+	RecoveryCodes []RecoveryCode `json:"recovery_codes,omitempty"`
+	LastCounter   *int64         `json:"last_counter,omitempty"` // Last time step accepted by validate-mfa
 }
 
 type MFAStorage struct {
@@ -85,6 +95,26 @@ func saveMFAStorage(storage *MFAStorage) error {
 	return nil
 }
 
+// lockMFAStorage takes an exclusive lock next to the MFA config file, so a
+// load-modify-save cycle isn't interleaved with another process's. The
+// lock is released by the returned function or when the process exits.
+func lockMFAStorage() (func(), error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(configPath+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock config file: %v", err)
+	}
+	return func() { file.Close() }, nil
+}
+
 func cleanSecret(secret string) string {
 	// Remove all non-base32 characters (spaces, hyphens, etc.)
 	// Base32 alphabet: A-Z, 2-7
@@ -192,6 +222,57 @@ func generateTOTP(secret string, period int) (string, int, error) {
 	return generateTOTPWithOffset(secret, period, 0)
 }
 
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := base32.StdEncoding.DecodeString(cleanSecret(secret))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key after cleaning: %v", err)
+	}
+	return key, nil
+}
+
+// totpValueAt returns the truncated HOTP value for the period containing t
+// and the seconds left in that period.
+func totpValueAt(secret string, period int, t time.Time) (uint32, int, error) {
+	if period <= 0 {
+		return 0, 0, fmt.Errorf("period must be greater than 0")
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := t.Unix()
+	counter := now / int64(period)
+	remaining := period - int(now%int64(period))
+
+	return otp.Value(key, uint64(counter)), remaining, nil
+}
+
+// totpAt computes the code valid at t without any debug output, for callers
+// that redraw the screen or otherwise can't tolerate the noise.
+func totpAt(secret string, period int, t time.Time) (string, int, error) {
+	value, remaining, err := totpValueAt(secret, period, t)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return fmt.Sprintf("%06d", value%1000000), remaining, nil
+}
+
+// codeAt generates the code for entry at t using the encoding its type
+// calls for.
+func codeAt(entry MFAEntry, t time.Time) (string, int, error) {
+	switch entry.Type {
+	case "", mfaTypeTOTP:
+		return totpAt(entry.Secret, entry.Period, t)
+	case mfaTypeSteam:
+		return steamCodeAt(entry.Secret, t)
+	default:
+		return "", 0, fmt.Errorf("unknown MFA type '%s'", entry.Type)
+	}
+}
+
 // Generate multiple TOTP codes with time offsets to help with synchronization
 func generateTOTPCodes(secret string, period int) error {
 	fmt.Println("=== Generating TOTP codes with different time offsets ===")
@@ -253,15 +334,48 @@ func testTOTP() {
 	fmt.Println("==========================================")
 }
 
-func SetupMFA(account, name, secret string, period int) error {
-	// Run test first
-	testTOTP()
-	
-	// Validate the secret by trying to generate a code
-	_, _, err := generateTOTP(secret, period)
+func SetupMFA(account, name, secret string, period int, mfaType string) error {
+	switch mfaType {
+	case "", mfaTypeTOTP:
+		mfaType = ""
+
+		// Run test first
+		testTOTP()
+
+		// Validate the secret by trying to generate a code
+		_, _, err := generateTOTP(secret, period)
+		if err != nil {
+			return fmt.Errorf("invalid secret key - cannot generate TOTP: %v", err)
+		}
+	case mfaTypeSteam:
+		normalized, err := normalizeSteamSecret(secret)
+		if err != nil {
+			return err
+		}
+		secret = normalized
+		period = steamGuardPeriod
+	default:
+		return fmt.Errorf("unknown MFA type '%s' (expected totp or steam)", mfaType)
+	}
+
+	return storeMFAEntry(MFAEntry{
+		Account: account,
+		Name:    name,
+		Secret:  secret,
+		Period:  period,
+		Type:    mfaType,
+	}, true)
+}
+
+// storeMFAEntry adds newEntry or, if replace is set, replaces the entry with
+// the same account and name while keeping its recovery codes. It prints
+// nothing, so callers holding a fresh secret don't echo it.
+func storeMFAEntry(newEntry MFAEntry, replace bool) error {
+	unlock, err := lockMFAStorage()
 	if err != nil {
-		return fmt.Errorf("invalid secret key - cannot generate TOTP: %v", err)
+		return err
 	}
+	defer unlock()
 
 	storage, err := loadMFAStorage()
 	if err != nil {
@@ -270,25 +384,17 @@ func SetupMFA(account, name, secret string, period int) error {
 
 	// Check if entry already exists and update it
 	for i, entry := range storage.Entries {
-		if entry.Account == account && entry.Name == name {
-			storage.Entries[i] = MFAEntry{
-				Account: account,
-				Name:    name,
-				Secret:  secret,
-				Period:  period,
+		if entry.Account == newEntry.Account && entry.Name == newEntry.Name {
+			if !replace {
+				return fmt.Errorf("%s (%s) already has an MFA secret; use --force to replace it", newEntry.Name, newEntry.Account)
 			}
+			newEntry.RecoveryCodes = entry.RecoveryCodes
+			storage.Entries[i] = newEntry
 			return saveMFAStorage(storage)
 		}
 	}
 
 	// Add new entry
-	newEntry := MFAEntry{
-		Account: account,
-		Name:    name,
-		Secret:  secret,
-		Period:  period,
-	}
-
 	storage.Entries = append(storage.Entries, newEntry)
 	return saveMFAStorage(storage)
 }
@@ -311,6 +417,10 @@ func GenerateMFA(account, name string) (string, int, error) {
 	// Find the matching entry
 	for _, entry := range storage.Entries {
 		if entry.Account == account && entry.Name == name {
+			if entry.Type == mfaTypeSteam {
+				return codeAt(entry, time.Now())
+			}
+
 			// Generate multiple codes with time offsets for debugging
 			fmt.Printf("\n=== Debugging codes for %s (%s) ===\n", name, account)
 			generateTOTPCodes(entry.Secret, entry.Period)
@@ -325,3 +435,67 @@ func GenerateMFA(account, name string) (string, int, error) {
 
 	return "", 0, fmt.Errorf("MFA entry not found for account '%s' and name '%s'", account, name)
 }
+
+func findMFAEntry(storage *MFAStorage, account, name string) (*MFAEntry, error) {
+	for i := range storage.Entries {
+		if storage.Entries[i].Account == account && storage.Entries[i].Name == name {
+			return &storage.Entries[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("MFA entry not found for account '%s' and name '%s'", account, name)
+}
+
+// GenerateMFAAt returns the code for the entry at t and the seconds left in
+// that period, without the debugging output of GenerateMFA.
+func GenerateMFAAt(account, name string, t time.Time) (string, int, error) {
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return "", 0, err
+	}
+
+	entry, err := findMFAEntry(storage, account, name)
+	if err != nil {
+		return "", 0, err
+	}
+
+	code, remaining, err := codeAt(*entry, t)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to generate TOTP: %v", err)
+	}
+	return code, remaining, nil
+}
+
+// WaitForFreshMFA blocks until the entry's current code has at least
+// minRemaining seconds of validity left, sleeping into the next period if
+// necessary.
+func WaitForFreshMFA(account, name string, minRemaining int) error {
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return err
+	}
+
+	entry, err := findMFAEntry(storage, account, name)
+	if err != nil {
+		return err
+	}
+
+	if entry.Period <= 0 {
+		return fmt.Errorf("period must be greater than 0")
+	}
+	if minRemaining > entry.Period {
+		return fmt.Errorf("minimum remaining time %ds exceeds the %ds period", minRemaining, entry.Period)
+	}
+
+	now := time.Now()
+	period := int64(entry.Period)
+	remaining := period - now.Unix()%period
+	if remaining >= int64(minRemaining) {
+		return nil
+	}
+
+	fmt.Printf("Only %d seconds left on the current code, waiting for the next one...\n", remaining)
+	boundary := time.Unix((now.Unix()/period+1)*period, 0)
+	time.Sleep(time.Until(boundary))
+	return nil
+}
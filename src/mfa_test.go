@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestWaitForFreshMFARejectsBadPeriod(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	storage := &MFAStorage{Entries: []MFAEntry{
+		{Account: "zero", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: 0},
+		{Account: "negative", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: -30},
+	}}
+	if err := saveMFAStorage(storage); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range storage.Entries {
+		if err := WaitForFreshMFA(entry.Account, entry.Name, 0); err == nil {
+			t.Errorf("period %d: expected an error", entry.Period)
+		}
+	}
+}
@@ -7,12 +7,79 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
 )
 
 type MPINEntry struct {
-	Name    string `json:"name"`
-	Account string `json:"account"`
-	PIN     string `json:"pin"`
+	Name    string    `json:"name"`
+	Account string    `json:"account"`
+	PIN     string    `json:"pin"`
+	Kind    string    `json:"kind,omitempty"`
+	Policy  PINPolicy `json:"policy,omitzero"`
+}
+
+// mpinKind describes the PINs a kind of issuer hands out.
+type mpinKind struct {
+	label         string
+	minLength     int
+	maxLength     int
+	defaultLength int
+	alphanumeric  bool
+}
+
+// mpinKindOrder is the order list-mpin groups entries in; "" covers
+// entries saved without a kind.
+var mpinKindOrder = []string{"card", "sim", "puk", "alarm", "app", ""}
+
+var mpinKinds = map[string]mpinKind{
+	"card":  {"Card PINs", 4, 12, 4, false}, // ISO 9564 allows 4 to 12 digits
+	"sim":   {"SIM PINs", 4, 8, 4, false},
+	"puk":   {"SIM PUK codes", 8, 8, 8, false},
+	"alarm": {"Alarm and door codes", 4, 8, 4, false},
+	"app":   {"App and device unlock codes", 4, 16, 6, true},
+	"":      {"Other", 1, 64, 4, false},
+}
+
+func lookupMPINKind(kind string) (mpinKind, error) {
+	spec, ok := mpinKinds[kind]
+	if !ok {
+		return mpinKind{}, fmt.Errorf("unknown PIN kind '%s' (use %s)", kind, strings.Join(mpinKindOrder[:len(mpinKindOrder)-1], ", "))
+	}
+	return spec, nil
+}
+
+func (k mpinKind) checkLength(length int) error {
+	if length < k.minLength || length > k.maxLength {
+		if k.minLength == k.maxLength {
+			return fmt.Errorf("PIN length must be %d for this kind", k.minLength)
+		}
+		return fmt.Errorf("PIN length must be %d to %d for this kind", k.minLength, k.maxLength)
+	}
+	return nil
+}
+
+// validatePIN checks an issued PIN against the rules of its kind.
+func validatePIN(pin string, kind string) error {
+	spec, err := lookupMPINKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := spec.checkLength(len(pin)); err != nil {
+		return err
+	}
+	for _, r := range pin {
+		isDigit := r >= '0' && r <= '9'
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isDigit && !(spec.alphanumeric && isLetter) {
+			if spec.alphanumeric {
+				return fmt.Errorf("PIN may only contain letters and digits")
+			}
+			return fmt.Errorf("PIN may only contain digits")
+		}
+	}
+	return nil
 }
 
 type MPINConfig struct {
@@ -97,12 +164,60 @@ func generateMPIN(length int) (string, error) {
 	return string(pin), nil
 }
 
-func AddMPIN(name, account string, length int) error {
+// promptConfirmedSecret reads a secret at prompt and, when stdin is a
+// terminal, again at repeat so a typo at the hidden prompt is caught. Piped
+// input is read once. ok reports whether the two entries matched.
+func promptConfirmedSecret(prompt, repeat string) (secret string, ok bool, err error) {
+	secret, err = promptSecret(prompt)
+	if err != nil || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return secret, err == nil, err
+	}
+	confirm, err := promptSecret(repeat)
+	if err != nil {
+		return "", false, err
+	}
+	return secret, secret == confirm, nil
+}
+
+// findMPINEntry returns the entry for name/account, if any, and the PINs of
+// every other entry.
+func findMPINEntry(config *MPINConfig, name, account string) (*MPINEntry, []string) {
+	var existing *MPINEntry
+	var otherPINs []string
+	for i, entry := range config.Entries {
+		if entry.Name == name && entry.Account == account {
+			existing = &config.Entries[i]
+		} else {
+			otherPINs = append(otherPINs, entry.PIN)
+		}
+	}
+	return existing, otherPINs
+}
+
+// resolveMPINSettings fills in kind and policy from an existing entry when
+// they were not given. A nil policy means strict for new entries.
+func resolveMPINSettings(existing *MPINEntry, kind string, policy *PINPolicy) (string, PINPolicy) {
+	entryPolicy := PINPolicy{}
+	if existing != nil {
+		if kind == "" {
+			kind = existing.Kind
+		}
+		entryPolicy = existing.Policy
+	}
+	if policy != nil {
+		entryPolicy = *policy
+	}
+	return kind, entryPolicy
+}
+
+// AddMPIN generates a PIN that passes the entry's PIN policy. A length of 0
+// uses the default length of kind.
+func AddMPIN(name, account, kind string, length int, policy *PINPolicy) error {
 	if name == "" || account == "" {
 		return fmt.Errorf("name and account cannot be empty")
 	}
 
-	if length <= 0 {
+	if length < 0 {
 		return fmt.Errorf("PIN length must be positive")
 	}
 
@@ -111,35 +226,46 @@ func AddMPIN(name, account string, length int) error {
 		return err
 	}
 
-	// Check if entry already exists
-	for i, entry := range config.Entries {
-		if entry.Name == name && entry.Account == account {
-			// Update existing entry
-			pin, err := generateMPIN(length)
-			if err != nil {
-				return err
-			}
-			config.Entries[i].PIN = pin
-			err = saveMPINConfig(config)
-			if err != nil {
-				return err
-			}
-			fmt.Printf("MPIN updated for %s (%s): %s\n", name, account, pin)
-			return nil
-		}
+	existing, otherPINs := findMPINEntry(config, name, account)
+	kind, entryPolicy := resolveMPINSettings(existing, kind, policy)
+
+	spec, err := lookupMPINKind(kind)
+	if err != nil {
+		return err
+	}
+	if length == 0 {
+		length = spec.defaultLength
+	}
+	if err := spec.checkLength(length); err != nil {
+		return err
 	}
 
-	// Generate new PIN
-	pin, err := generateMPIN(length)
+	pin, err := generatePINWithPolicy(length, entryPolicy, otherPINs)
 	if err != nil {
 		return err
 	}
 
+	// Check if entry already exists
+	if existing != nil {
+		// Update existing entry
+		existing.PIN = pin
+		existing.Kind = kind
+		existing.Policy = entryPolicy
+		err = saveMPINConfig(config)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("MPIN updated for %s (%s): %s\n", name, account, pin)
+		return nil
+	}
+
 	// Add new entry
 	newEntry := MPINEntry{
 		Name:    name,
 		Account: account,
 		PIN:     pin,
+		Kind:    kind,
+		Policy:  entryPolicy,
 	}
 
 	config.Entries = append(config.Entries, newEntry)
@@ -153,6 +279,50 @@ func AddMPIN(name, account string, length int) error {
 	return nil
 }
 
+// SetMPIN stores a PIN that was issued rather than generated. Weak or reused
+// PINs are stored with a warning since they can't be chosen.
+func SetMPIN(name, account, kind, pin string, policy *PINPolicy) error {
+	if name == "" || account == "" {
+		return fmt.Errorf("name and account cannot be empty")
+	}
+
+	config, err := loadMPINConfig()
+	if err != nil {
+		return err
+	}
+
+	existing, otherPINs := findMPINEntry(config, name, account)
+	kind, entryPolicy := resolveMPINSettings(existing, kind, policy)
+
+	if err := validatePIN(pin, kind); err != nil {
+		return err
+	}
+	if reason := pinPolicyViolation(pin, entryPolicy, otherPINs); reason != "" {
+		fmt.Printf("Warning: weak PIN (%s); change it with the issuer if you can\n", reason)
+	}
+
+	if existing != nil {
+		existing.PIN = pin
+		existing.Kind = kind
+		existing.Policy = entryPolicy
+	} else {
+		config.Entries = append(config.Entries, MPINEntry{
+			Name:    name,
+			Account: account,
+			PIN:     pin,
+			Kind:    kind,
+			Policy:  entryPolicy,
+		})
+	}
+
+	if err := saveMPINConfig(config); err != nil {
+		return err
+	}
+
+	fmt.Printf("MPIN stored for %s (%s)\n", name, account)
+	return nil
+}
+
 func GetMPIN(name, account string) error {
 	if name == "" || account == "" {
 		return fmt.Errorf("name and account cannot be empty")
@@ -186,8 +356,21 @@ func ListMPINs() error {
 	}
 
 	fmt.Println("MPIN Entries:")
-	for _, entry := range config.Entries {
-		fmt.Printf("  Name: %s, Account: %s, PIN: %s\n", entry.Name, entry.Account, entry.PIN)
+	for _, kind := range mpinKindOrder {
+		var entries []MPINEntry
+		for _, entry := range config.Entries {
+			if entry.Kind == kind {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		fmt.Printf("%s:\n", mpinKinds[kind].label)
+		for _, entry := range entries {
+			fmt.Printf("  Name: %s, Account: %s, PIN: %s, Policy: %s\n", entry.Name, entry.Account, entry.PIN, entry.Policy)
+		}
 	}
 
 	return nil
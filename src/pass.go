@@ -2,12 +2,19 @@ package main
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type PasswordEntry struct {
@@ -15,7 +22,22 @@ type PasswordEntry struct {
 	Account  string `json:"account"`
 	Password string `json:"password"`
 	Length   int    `json:"length"`
-	Config   string `json:"config"` // Store what character types were used
+	Config   string `json:"config"`           // Store what character types were used
+	Rules    string `json:"rules,omitempty"`  // passwordrules string the password was generated from
+	Policy   string `json:"policy,omitempty"` // Named generator policy the password was generated from
+	Source   string `json:"source,omitempty"` // Password manager the entry was imported from
+	Group    string `json:"group,omitempty"`  // Folder path in that password manager, e.g. Internet/Social
+
+	Notes string   `json:"notes,omitempty"`
+	URIs  []string `json:"uris,omitempty"`
+
+	Generator *GeneratorPolicy `json:"generator,omitempty"`  // Exact settings used, so rotate can repeat them
+	UpdatedAt *time.Time       `json:"updated_at,omitempty"` // When Password was last set
+
+	// A rotated password waits here until the change has been made on the
+	// service and confirmed with rotate --confirm.
+	PendingPassword string     `json:"pending_password,omitempty"`
+	PendingSince    *time.Time `json:"pending_since,omitempty"`
 }
 
 type PasswordStorage struct {
@@ -82,148 +104,706 @@ func savePasswordStorage(storage *PasswordStorage) error {
 	return nil
 }
 
-func generatePassword(length int, useSmallAlpha, useLargeAlpha, useDigits bool, specialChars string) (string, error) {
-	if length <= 0 {
-		return "", fmt.Errorf("password length must be greater than 0")
+// randomInt returns a uniformly random integer in [0, n) from crypto/rand.
+func randomInt(n int) (int, error) {
+	value, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %v", err)
 	}
+	return int(value.Int64()), nil
+}
 
-	var charset string
-	var configParts []string
+// randomBigInt returns a uniformly random integer in [0, n) from
+// crypto/rand.
+func randomBigInt(n *big.Int) (*big.Int, error) {
+	value, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate random number: %v", err)
+	}
+	return value, nil
+}
+
+// shuffleCharacters puts chars in a uniformly random order (Fisher-Yates).
+func shuffleCharacters(chars []string) error {
+	for i := len(chars) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return err
+		}
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+	return nil
+}
+
+// randomBytes returns n bytes from crypto/rand.
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %v", err)
+	}
+	return buf, nil
+}
+
+const (
+	lowercaseChars      = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseChars      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars          = "0123456789"
+	defaultSpecialChars = "!@#$%^&*()_+-=[]{}|;:,.<>?"
+
+	// Characters that are easily confused with one another when read or
+	// typed from a printout.
+	ambiguousChars = "0O1lI|"
+
+	// Required sets and the consecutive-character limit are met by
+	// rejection, which gives up after this many attempts so impossible
+	// combinations fail instead of spinning.
+	maxGenerateAttempts = 100000
+)
+
+// Character class names, used as keys for ClassLimits.
+const (
+	classLower   = "lower"
+	classUpper   = "upper"
+	classDigits  = "digits"
+	classSpecial = "special"
+)
+
+// ClassLimits bounds how many characters of one class a password may
+// contain. A Max of 0 means no upper bound; --max rejects 0, so a class is
+// excluded by not selecting it.
+type ClassLimits struct {
+	Min int `json:"min"`
+	Max int `json:"max,omitempty"`
+}
+
+type PasswordOptions struct {
+	Length       int                    `json:"length"`
+	SmallAlpha   bool                   `json:"lowercase,omitempty"`
+	LargeAlpha   bool                   `json:"uppercase,omitempty"`
+	Digits       bool                   `json:"digits,omitempty"`
+	SpecialChars string                 `json:"special,omitempty"`
+	NoAmbiguous  bool                   `json:"no_ambiguous,omitempty"`
+	Limits       map[string]ClassLimits `json:"limits,omitempty"` // Selected classes default to Min 1
+
+	RequiredSets   []string `json:"required_sets,omitempty"`   // Extra sets that must each contribute a character
+	MaxConsecutive int      `json:"max_consecutive,omitempty"` // Longest run of one repeated character, 0 for no limit
+	Rules          string   `json:"rules,omitempty"`           // passwordrules string the options were parsed from
+
+	Layouts         []string `json:"layouts,omitempty"`           // Keyboard layouts, the first one is typed on
+	Typeable        bool     `json:"typeable,omitempty"`          // Keep only characters typeable directly on all Layouts
+	MaxShiftToggles int      `json:"max_shift_toggles,omitempty"` // Shift presses/releases allowed on Layouts[0], 0 for no limit
+}
+
+type charClass struct {
+	name   string
+	chars  string
+	limits ClassLimits
+}
+
+// splitCharacters splits s into user-perceived characters: a base rune
+// together with any combining marks, variation selectors, emoji modifiers
+// and zero-width-joiner sequences that follow it. This covers the alphabets
+// people pass to -s without a full UAX #29 implementation.
+func splitCharacters(s string) []string {
+	var chars []string
+	joinNext := false
+	regionalPending := false
+	for i, r := range s {
+		extends := unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+			(r >= 0xFE00 && r <= 0xFE0F) || (r >= 0xE0100 && r <= 0xE01EF) || // Variation selectors
+			(r >= 0x1F3FB && r <= 0x1F3FF) || // Skin tone modifiers
+			r == 0x200D
+		regional := r >= 0x1F1E6 && r <= 0x1F1FF // Flag halves pair up
+
+		if len(chars) > 0 && (extends || joinNext || (regional && regionalPending)) {
+			chars[len(chars)-1] += string(r)
+			regionalPending = false
+		} else {
+			chars = append(chars, s[i:i+utf8.RuneLen(r)])
+			regionalPending = regional
+		}
+		joinNext = r == 0x200D
+	}
+	return chars
+}
+
+// characterCount is the length of s as the generator counts it.
+func characterCount(s string) int {
+	return len(splitCharacters(s))
+}
+
+// uniqueCharacters returns the distinct characters of s in order, so a
+// character listed twice isn't picked twice as often.
+func uniqueCharacters(s string) []string {
+	seen := make(map[string]bool)
+	var chars []string
+	for _, c := range splitCharacters(s) {
+		if !seen[c] {
+			seen[c] = true
+			chars = append(chars, c)
+		}
+	}
+	return chars
+}
+
+func removeChars(s, remove string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(remove, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// classes returns the selected character classes with their limits. When
+// nothing is selected every class is used, as before.
+func (opts PasswordOptions) classes() []charClass {
+	var classes []charClass
+	add := func(name, chars string) {
+		if opts.NoAmbiguous {
+			chars = removeChars(chars, ambiguousChars)
+		}
+		if opts.Typeable {
+			chars = keepTypeable(chars, opts.typingLayouts())
+		}
+		limits, ok := opts.Limits[name]
+		if !ok {
+			limits = ClassLimits{Min: 1}
+		}
+		classes = append(classes, charClass{name: name, chars: chars, limits: limits})
+	}
+
+	if opts.SmallAlpha {
+		add(classLower, lowercaseChars)
+	}
+	if opts.LargeAlpha {
+		add(classUpper, uppercaseChars)
+	}
+	if opts.Digits {
+		add(classDigits, digitChars)
+	}
+	if opts.SpecialChars != "" {
+		// Letters or digits listed in -s belong to their own class, so
+		// each character is counted against exactly one class's limits.
+		special := opts.SpecialChars
+		for _, class := range classes {
+			special = removeChars(special, class.chars)
+		}
+		add(classSpecial, special)
+	}
 
-	// Build character set based on flags
-	if useSmallAlpha {
-		charset += "abcdefghijklmnopqrstuvwxyz"
+	// If no character types specified, use all by default
+	if len(classes) == 0 {
+		all := opts
+		all.SmallAlpha, all.LargeAlpha, all.Digits = true, true, true
+		all.SpecialChars = defaultSpecialChars
+		return all.classes()
+	}
+
+	return classes
+}
+
+// typingLayouts returns the layouts the password will be typed on, US if
+// none were chosen.
+func (opts PasswordOptions) typingLayouts() []string {
+	if len(opts.Layouts) == 0 {
+		return []string{"us"}
+	}
+	return opts.Layouts
+}
+
+// config describes the options in the human-readable form stored on each
+// entry.
+func (opts PasswordOptions) config() string {
+	var configParts []string
+	if opts.SmallAlpha {
 		configParts = append(configParts, "lowercase")
 	}
-	if useLargeAlpha {
-		charset += "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	if opts.LargeAlpha {
 		configParts = append(configParts, "uppercase")
 	}
-	if useDigits {
-		charset += "0123456789"
+	if opts.Digits {
 		configParts = append(configParts, "digits")
 	}
-	if specialChars != "" {
-		charset += specialChars
-		configParts = append(configParts, fmt.Sprintf("special(%s)", specialChars))
+	if opts.SpecialChars != "" {
+		configParts = append(configParts, fmt.Sprintf("special(%s)", opts.SpecialChars))
 	}
 
-	// Debug: Print what's being used for password generation
-	fmt.Printf("Debug: Using charset: %s\n", charset)
-	fmt.Printf("Debug: Special chars: '%s'\n", specialChars)
-	fmt.Printf("Debug: Config parts: %v\n", configParts)
+	config := strings.Join(configParts, ", ")
+	if config == "" {
+		config = "all (default)"
+	}
 
-	// If no character types specified, use all by default
-	if charset == "" {
-		charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()_+-=[]{}|;:,.<>?"
-		configParts = []string{"all"}
+	if opts.NoAmbiguous {
+		config += ", no-ambiguous"
+	}
+
+	var names []string
+	for name := range opts.Limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		limits := opts.Limits[name]
+		if limits.Max > 0 {
+			config += fmt.Sprintf(", %s %d-%d", name, limits.Min, limits.Max)
+		} else {
+			config += fmt.Sprintf(", %s %d+", name, limits.Min)
+		}
+	}
+
+	for _, set := range opts.RequiredSets {
+		config += fmt.Sprintf(", one of [%s]", set)
+	}
+	if opts.MaxConsecutive > 0 {
+		config += fmt.Sprintf(", max-consecutive %d", opts.MaxConsecutive)
+	}
+	if opts.Typeable {
+		config += fmt.Sprintf(", typeable(%s)", strings.Join(opts.typingLayouts(), ","))
+	}
+	if opts.MaxShiftToggles > 0 {
+		config += fmt.Sprintf(", max-shift-toggles %d", opts.MaxShiftToggles)
+	}
+
+	return config
+}
+
+func validateClasses(classes []charClass, opts PasswordOptions) error {
+	if _, err := parseLayouts(strings.Join(opts.Layouts, ",")); err != nil {
+		return err
+	}
+	if opts.MaxShiftToggles < 0 {
+		return fmt.Errorf("max-shift-toggles must not be negative")
+	}
+
+	for name := range opts.Limits {
+		selected := false
+		for _, class := range classes {
+			selected = selected || class.name == name
+		}
+		if !selected {
+			return fmt.Errorf("limits given for class '%s', which is not selected", name)
+		}
+	}
+
+	length := opts.Length
+	minTotal, maxTotal, unbounded := len(opts.RequiredSets), 0, false
+	for _, class := range classes {
+		if class.chars == "" {
+			return fmt.Errorf("no characters left in class '%s'", class.name)
+		}
+		if class.limits.Min < 0 || class.limits.Max < 0 {
+			return fmt.Errorf("limits for class '%s' must not be negative", class.name)
+		}
+		if class.limits.Max > 0 && class.limits.Min > class.limits.Max {
+			return fmt.Errorf("minimum for class '%s' is greater than its maximum", class.name)
+		}
+		minTotal += class.limits.Min
+		if class.limits.Max == 0 {
+			unbounded = true
+		}
+		maxTotal += class.limits.Max
+	}
+
+	if minTotal > length {
+		return fmt.Errorf("password length %d is too short for the required %d characters", length, minTotal)
+	}
+	if !unbounded && maxTotal < length {
+		return fmt.Errorf("class maximums allow at most %d characters, but length is %d", maxTotal, length)
+	}
+	return nil
+}
+
+// satisfiesRules reports whether password, split into characters, meets
+// the required sets and the consecutive-character limit.
+func satisfiesRules(password []string, opts PasswordOptions) bool {
+	for _, set := range opts.RequiredSets {
+		inSet := make(map[string]bool)
+		for _, c := range splitCharacters(set) {
+			inSet[c] = true
+		}
+		found := false
+		for _, c := range password {
+			if inSet[c] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if opts.MaxConsecutive > 0 {
+		run := 0
+		for i, c := range password {
+			if i > 0 && c == password[i-1] {
+				run++
+			} else {
+				run = 1
+			}
+			if run > opts.MaxConsecutive {
+				return false
+			}
+		}
 	}
 
-	if len(charset) == 0 {
-		return "", fmt.Errorf("no characters available for password generation")
+	return true
+}
+
+// classCountTable holds, for each class j and length r, the log2 of the
+// number of strings of length r that use only classes j and later, each
+// within its limits. Logs keep long passwords cheap; the float rounding
+// they add is far below anything a sample could show.
+type classCountTable struct {
+	classes []charClass
+	sizes   []int
+	logWays [][]float64
+}
+
+func newClassCountTable(classes []charClass, sizes []int, length int) *classCountTable {
+	t := &classCountTable{classes: classes, sizes: sizes, logWays: make([][]float64, len(classes)+1)}
+	for j := range t.logWays {
+		t.logWays[j] = make([]float64, length+1)
+		for r := range t.logWays[j] {
+			t.logWays[j][r] = math.Inf(-1)
+		}
 	}
+	t.logWays[len(classes)][0] = 0
 
-	// Generate password
-	password := make([]byte, length)
-	charsetLen := big.NewInt(int64(len(charset)))
+	for j := len(classes) - 1; j >= 0; j-- {
+		for r := 0; r <= length; r++ {
+			t.logWays[j][r] = logSum(t.logWeights(j, r))
+		}
+	}
+	return t
+}
 
-	for i := 0; i < length; i++ {
-		randomIndex, err := rand.Int(rand.Reader, charsetLen)
+func (t *classCountTable) maxCount(j, r int) int {
+	if t.classes[j].limits.Max > 0 {
+		return min(t.classes[j].limits.Max, r)
+	}
+	return r
+}
+
+// logWeights returns, for every allowed count c of class j, the log2 of
+// the number of strings of length r in which class j has c characters and
+// the later classes fill the rest. Index 0 is the class minimum.
+func (t *classCountTable) logWeights(j, r int) []float64 {
+	var weights []float64
+	for c := t.classes[j].limits.Min; c <= t.maxCount(j, r); c++ {
+		weights = append(weights, logBinomial(r, c)+float64(c)*math.Log2(float64(t.sizes[j]))+t.logWays[j+1][r-c])
+	}
+	return weights
+}
+
+// counts picks how many characters of length each class gets. Each split is
+// weighted by the number of passwords that have it, so drawing the
+// characters and shuffling them afterwards is uniform over all passwords
+// that meet the limits.
+func (t *classCountTable) counts(length int) ([]int, error) {
+	if math.IsInf(t.logWays[0][length], -1) {
+		return nil, fmt.Errorf("no password of length %d meets the character class limits", length)
+	}
+
+	counts := make([]int, len(t.classes))
+	remaining := length
+	for j := range t.classes {
+		weights := t.logWeights(j, remaining)
+		pick, err := randomLogWeighted(weights)
 		if err != nil {
-			return "", fmt.Errorf("failed to generate random number: %v", err)
+			return nil, err
 		}
-		password[i] = charset[randomIndex.Int64()]
+		counts[j] = t.classes[j].limits.Min + pick
+		remaining -= counts[j]
 	}
+	return counts, nil
+}
 
-	return string(password), nil
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return (a - b - c) / math.Ln2
 }
 
-func AddPassword(name, account string, length int, useSmallAlpha, useLargeAlpha, useDigits bool, specialChars string) error {
-	if name == "" || account == "" {
-		return fmt.Errorf("name and account are required")
+// logSum returns log2 of the sum of 2^w over weights.
+func logSum(weights []float64) float64 {
+	top := math.Inf(-1)
+	for _, w := range weights {
+		top = math.Max(top, w)
 	}
+	if math.IsInf(top, -1) {
+		return top
+	}
+	sum := 0.0
+	for _, w := range weights {
+		sum += math.Exp2(w - top)
+	}
+	return top + math.Log2(sum)
+}
 
-	// Generate password
-	password, err := generatePassword(length, useSmallAlpha, useLargeAlpha, useDigits, specialChars)
+// randomLogWeighted picks an index with probability proportional to 2^w.
+func randomLogWeighted(weights []float64) (int, error) {
+	top := logSum(weights)
+	buf, err := randomBytes(8)
 	if err != nil {
-		return fmt.Errorf("failed to generate password: %v", err)
+		return 0, err
 	}
+	u := float64(binary.BigEndian.Uint64(buf)>>11) / (1 << 53)
 
-	// Build config string for storage
-	var configParts []string
-	if useSmallAlpha {
-		configParts = append(configParts, "lowercase")
+	last := 0
+	for i, w := range weights {
+		if math.IsInf(w, -1) {
+			continue
+		}
+		last = i
+		if u -= math.Exp2(w - top); u < 0 {
+			return i, nil
+		}
 	}
-	if useLargeAlpha {
-		configParts = append(configParts, "uppercase")
+	return last, nil
+}
+
+// classesCharset returns the distinct characters of all classes.
+func classesCharset(classes []charClass) []string {
+	var charset string
+	for _, class := range classes {
+		charset += class.chars
 	}
-	if useDigits {
-		configParts = append(configParts, "digits")
+	return uniqueCharacters(charset)
+}
+
+// passwordEntropy is the entropy in bits of a password drawn uniformly from
+// all passwords that meet the class limits. Required sets and the Shift
+// toggle limit make it a slight overestimate.
+func passwordEntropy(opts PasswordOptions) float64 {
+	classes := opts.classes()
+	charset := classesCharset(classes)
+	if opts.MaxShiftToggles > 0 {
+		return math.Log2(shiftLimitedCount(charset, opts.Length, opts.typingLayouts()[0], opts.MaxShiftToggles))
 	}
-	if specialChars != "" {
-		configParts = append(configParts, fmt.Sprintf("special(%s)", specialChars))
+	if opts.Length <= 0 || validateClasses(classes, opts) != nil {
+		return float64(opts.Length) * math.Log2(float64(len(charset)))
 	}
-	
-	config := strings.Join(configParts, ", ")
-	if config == "" {
-		config = "all (default)"
+
+	sizes := make([]int, len(classes))
+	for j, class := range classes {
+		sizes[j] = len(uniqueCharacters(class.chars))
+	}
+	return newClassCountTable(classes, sizes, opts.Length).logWays[0][opts.Length]
+}
+
+// generatePassword builds the password from the class limits: it picks
+// how many characters each class gets, draws them uniformly from their
+// class and shuffles the result. With a Shift toggle limit the shuffled
+// characters are then grouped into few enough shifted and unshifted runs;
+// the class split is weighted without the limit, so that case is close to
+// but not exactly uniform. Characters may be multi-byte or combined
+// sequences; Length counts characters, not bytes.
+func generatePassword(opts PasswordOptions) (string, error) {
+	if opts.Length <= 0 {
+		return "", fmt.Errorf("password length must be greater than 0")
 	}
 
+	classes := opts.classes()
+	if err := validateClasses(classes, opts); err != nil {
+		return "", err
+	}
+
+	charset := classesCharset(classes)
+
+	// Debug: Print what's being used for password generation
+	fmt.Printf("Debug: Using charset: %s\n", strings.Join(charset, ""))
+	fmt.Printf("Debug: Special chars: '%s'\n", opts.SpecialChars)
+	fmt.Printf("Debug: Config: %s\n", opts.config())
+
+	pools := make([][]string, len(classes))
+	sizes := make([]int, len(classes))
+	for j, class := range classes {
+		pools[j] = uniqueCharacters(class.chars)
+		sizes[j] = len(pools[j])
+	}
+
+	table := newClassCountTable(classes, sizes, opts.Length)
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		counts, err := table.counts(opts.Length)
+		if err != nil {
+			return "", err
+		}
+
+		var password []string
+		for j, count := range counts {
+			for i := 0; i < count; i++ {
+				index, err := randomInt(sizes[j])
+				if err != nil {
+					return "", err
+				}
+				password = append(password, pools[j][index])
+			}
+		}
+		if err := shuffleCharacters(password); err != nil {
+			return "", err
+		}
+		if opts.MaxShiftToggles > 0 {
+			if password, err = arrangeShift(password, opts.typingLayouts()[0], opts.MaxShiftToggles); err != nil {
+				return "", err
+			}
+		}
+
+		if satisfiesRules(password, opts) {
+			return strings.Join(password, ""), nil
+		}
+	}
+
+	return "", fmt.Errorf("could not satisfy the required sets and consecutive-character limit, try relaxing them")
+}
+
+// parseClassLimits parses "class=N,class=N" as given to --min or --max.
+func parseClassLimits(spec string) (map[string]int, error) {
+	values := make(map[string]int)
+	if spec == "" {
+		return values, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid class limit '%s' (expected class=N)", part)
+		}
+		switch name {
+		case classLower, classUpper, classDigits, classSpecial:
+		default:
+			return nil, fmt.Errorf("unknown character class '%s' (expected lower, upper, digits or special)", name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count for class '%s': %v", name, err)
+		}
+		values[name] = n
+	}
+
+	return values, nil
+}
+
+// buildClassLimits merges --min and --max values; classes given only a
+// maximum keep the default minimum of 1.
+func buildClassLimits(mins, maxs map[string]int) map[string]ClassLimits {
+	limits := make(map[string]ClassLimits)
+	for name, min := range mins {
+		limits[name] = ClassLimits{Min: min}
+	}
+	for name, max := range maxs {
+		l, ok := limits[name]
+		if !ok {
+			l.Min = 1
+		}
+		l.Max = max
+		limits[name] = l
+	}
+	return limits
+}
+
+func AddPassword(name, account string, opts PasswordOptions) error {
+	return AddPasswordWithPolicy(name, account, GeneratorPolicy{Password: &opts})
+}
+
+// storePasswordEntry adds entry, or replaces the existing entry with the same
+// name and account.
+func storePasswordEntry(entry PasswordEntry) error {
 	storage, err := loadPasswordStorage()
 	if err != nil {
 		return err
 	}
 
 	// Check if entry already exists and update it
-	for i, entry := range storage.Entries {
-		if entry.Name == name && entry.Account == account {
-			storage.Entries[i] = PasswordEntry{
-				Name:     name,
-				Account:  account,
-				Password: password,
-				Length:   length,
-				Config:   config,
-			}
-			fmt.Printf("Password updated for %s (%s): %s\n", name, account, password)
+	for i, existing := range storage.Entries {
+		if existing.Name == entry.Name && existing.Account == entry.Account {
+			storage.Entries[i] = entry
+			fmt.Printf("Password updated for %s (%s): %s\n", entry.Name, entry.Account, entry.Password)
 			return savePasswordStorage(storage)
 		}
 	}
 
 	// Add new entry
-	newEntry := PasswordEntry{
-		Name:     name,
-		Account:  account,
-		Password: password,
-		Length:   length,
-		Config:   config,
-	}
-
-	storage.Entries = append(storage.Entries, newEntry)
-	fmt.Printf("Password generated for %s (%s): %s\n", name, account, password)
+	storage.Entries = append(storage.Entries, entry)
+	fmt.Printf("Password generated for %s (%s): %s\n", entry.Name, entry.Account, entry.Password)
 	return savePasswordStorage(storage)
 }
 
-func GetPasswords() error {
+// printPasswordField prints a password line of GetPasswords in the given
+// display mode. Multi-line modes start on the line after the label.
+func printPasswordField(label, password, mode, note string) {
+	lines := formatPassword(password, mode)
+	if len(lines) == 1 {
+		fmt.Printf("   %s: %s%s\n", label, lines[0], note)
+		return
+	}
+	fmt.Printf("   %s:%s\n", label, note)
+	for _, line := range lines {
+		if line == "" {
+			fmt.Println()
+		} else {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+}
+
+// GetPasswords prints the stored passwords, optionally only those matching
+// name and account, with each password shown in the given display mode.
+func GetPasswords(name, account, mode string) error {
 	storage, err := loadPasswordStorage()
 	if err != nil {
 		return fmt.Errorf("error loading passwords: %v", err)
 	}
 
-	if len(storage.Entries) == 0 {
-		fmt.Println("No passwords found")
-		return nil
-	}
-
-	fmt.Println("Stored Passwords:")
-	fmt.Println("=================")
+	shown := 0
 	for i, entry := range storage.Entries {
+		if (name != "" && entry.Name != name) || (account != "" && entry.Account != account) {
+			continue
+		}
+		if shown == 0 {
+			fmt.Println("Stored Passwords:")
+			fmt.Println("=================")
+		}
+		shown++
+
 		fmt.Printf("%d. Name: %s\n", i+1, entry.Name)
 		fmt.Printf("   Account: %s\n", entry.Account)
-		fmt.Printf("   Password: %s\n", entry.Password)
+		if entry.Group != "" {
+			fmt.Printf("   Group: %s\n", entry.Group)
+		}
+		printPasswordField("Password", entry.Password, mode, "")
 		fmt.Printf("   Length: %d characters\n", entry.Length)
 		fmt.Printf("   Config: %s\n", entry.Config)
+		if len(entry.URIs) > 0 {
+			fmt.Printf("   URIs: %s\n", strings.Join(entry.URIs, ", "))
+		}
+		if entry.Notes != "" {
+			fmt.Printf("   Notes: %s\n", strings.ReplaceAll(entry.Notes, "\n", "\n          "))
+		}
+		strength := estimateStrength(entry.Password, entry.Name, entry.Account)
+		fmt.Printf("   Strength: %s\n", strength.summary())
+		if strength.Warning != "" {
+			fmt.Printf("   Warning: %s\n", strength.Warning)
+		}
+		if entry.UpdatedAt != nil {
+			fmt.Printf("   Updated: %s\n", entry.UpdatedAt.Format("2006-01-02"))
+		}
+		if entry.PendingPassword != "" {
+			since := "on an unknown date"
+			if entry.PendingSince != nil {
+				since = entry.PendingSince.Format("2006-01-02")
+			}
+			note := fmt.Sprintf(" (rotated %s, not yet confirmed)", since)
+			printPasswordField("Pending", entry.PendingPassword, mode, note)
+		}
 		fmt.Println()
 	}
 
+	if shown == 0 {
+		fmt.Println("No passwords found")
+	}
+
 	return nil
 }
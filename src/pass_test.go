@@ -0,0 +1,197 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCharacters(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"abc", []string{"a", "b", "c"}},
+		{"e\u0301", []string{"e\u0301"}},
+		{"e\u0301x", []string{"e\u0301", "x"}},
+		{"a\u0308\u0304", []string{"a\u0308\u0304"}},
+		{"\u2764\ufe0f!", []string{"\u2764\ufe0f", "!"}},
+		{"👍🏽👍", []string{"👍🏽", "👍"}},
+		{"👩\u200d💻a", []string{"👩\u200d💻", "a"}},
+		{"🇫🇷🇩🇪", []string{"🇫🇷", "🇩🇪"}},
+		{"🇫🇷🇩", []string{"🇫🇷", "🇩"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := splitCharacters(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCharacters(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestGeneratePasswordUniform checks with a chi-square test that every
+// character of a mixed ASCII, accented and emoji alphabet is drawn equally
+// often, whatever its length in bytes or runes.
+func TestGeneratePasswordUniform(t *testing.T) {
+	alphabet := []string{"a", "Z", "9", "é", "e\u0301", "ñ", "🙂", "👍🏽", "🇫🇷", "👩\u200d💻"}
+	opts := PasswordOptions{Length: 32, SpecialChars: strings.Join(alphabet, "")}
+
+	counts := make(map[string]int)
+	const passwords = 2000
+	for i := 0; i < passwords; i++ {
+		password, err := generatePassword(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chars := splitCharacters(password)
+		if len(chars) != opts.Length {
+			t.Fatalf("%q has %d characters, want %d", password, len(chars), opts.Length)
+		}
+		for _, c := range chars {
+			counts[c]++
+		}
+	}
+	if len(counts) != len(alphabet) {
+		t.Fatalf("drew %d distinct characters, want %d: %q", len(counts), len(alphabet), counts)
+	}
+
+	expected := float64(passwords*opts.Length) / float64(len(alphabet))
+	chiSquare := 0.0
+	for _, c := range alphabet {
+		d := float64(counts[c]) - expected
+		chiSquare += d * d / expected
+	}
+	// 27.88 is the 0.999 quantile of chi-square with 9 degrees of freedom.
+	if chiSquare > 27.88 {
+		t.Errorf("chi-square %.2f exceeds 27.88, counts %v", chiSquare, counts)
+	}
+}
+
+func classOf(c string) string {
+	switch {
+	case strings.Contains(lowercaseChars, c):
+		return classLower
+	case strings.Contains(uppercaseChars, c):
+		return classUpper
+	case strings.Contains(digitChars, c):
+		return classDigits
+	}
+	return classSpecial
+}
+
+// TestGeneratePasswordClassLimits draws many passwords per setting and
+// checks that every selected class appears within its --min/--max bounds.
+func TestGeneratePasswordClassLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     PasswordOptions
+		min, max string
+	}{
+		{"defaults", PasswordOptions{Length: 8}, "", ""},
+		{"all classes at minimum length", PasswordOptions{Length: 4, SmallAlpha: true, LargeAlpha: true, Digits: true, SpecialChars: "!@#"}, "", ""},
+		{"minimums", PasswordOptions{Length: 12, SmallAlpha: true, Digits: true, SpecialChars: "!@#"}, "digits=4,special=3", ""},
+		{"maximums", PasswordOptions{Length: 10, SmallAlpha: true, LargeAlpha: true, Digits: true}, "", "upper=1,digits=2"},
+		{"exact counts", PasswordOptions{Length: 6, SmallAlpha: true, Digits: true}, "lower=2,digits=4", "lower=2,digits=4"},
+		{"optional class", PasswordOptions{Length: 5, SmallAlpha: true, SpecialChars: "!"}, "special=0", "special=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mins, err := parseClassLimits(tt.min)
+			if err != nil {
+				t.Fatal(err)
+			}
+			maxs, err := parseClassLimits(tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := tt.opts
+			opts.Limits = buildClassLimits(mins, maxs)
+			classes := opts.classes()
+
+			for i := 0; i < 300; i++ {
+				password, err := generatePassword(opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				chars := splitCharacters(password)
+				if len(chars) != opts.Length {
+					t.Fatalf("%q has %d characters, want %d", password, len(chars), opts.Length)
+				}
+
+				counts := make(map[string]int)
+				for _, c := range chars {
+					counts[classOf(c)]++
+				}
+				for _, class := range classes {
+					n := counts[class.name]
+					if n < class.limits.Min || (class.limits.Max > 0 && n > class.limits.Max) {
+						t.Fatalf("%q has %d %s characters, want %d-%d", password, n, class.name, class.limits.Min, class.limits.Max)
+					}
+					delete(counts, class.name)
+				}
+				if len(counts) > 0 {
+					t.Fatalf("%q has characters of unselected classes %v", password, counts)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratePasswordRejectsImpossibleLimits(t *testing.T) {
+	letters := PasswordOptions{SmallAlpha: true, LargeAlpha: true}
+	tests := []struct {
+		name     string
+		length   int
+		opts     PasswordOptions
+		min, max string
+	}{
+		{"minimums exceed length", 4, letters, "lower=3,upper=2", ""},
+		{"defaults exceed length", 3, PasswordOptions{}, "", ""},
+		{"maximums below length", 10, letters, "", "lower=2,upper=2"},
+		{"minimum above maximum", 10, letters, "lower=5", "lower=3"},
+		{"unselected class", 10, letters, "digits=1", ""},
+		{"negative minimum", 10, letters, "lower=-1", ""},
+		{"negative maximum", 10, letters, "", "upper=-2"},
+		{"zero length", 0, letters, "", ""},
+		{"negative length", -5, letters, "", ""},
+	}
+
+	for _, tt := range tests {
+		mins, err := parseClassLimits(tt.min)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxs, err := parseClassLimits(tt.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := tt.opts
+		opts.Length = tt.length
+		opts.Limits = buildClassLimits(mins, maxs)
+		if password, err := generatePassword(opts); err == nil {
+			t.Errorf("%s: generated %q, want an error", tt.name, password)
+		}
+	}
+
+	for _, spec := range []string{"lower", "lower=x", "symbols=2"} {
+		if _, err := parseClassLimits(spec); err == nil {
+			t.Errorf("parseClassLimits(%q): want an error", spec)
+		}
+	}
+}
+
+func TestGeneratePasswordLength(t *testing.T) {
+	for _, length := range []int{1, 2, 16, 64, 1024} {
+		opts := PasswordOptions{Length: length, SmallAlpha: true, SpecialChars: "é👍🏽"}
+		opts.Limits = map[string]ClassLimits{classSpecial: {Min: 0}}
+		password, err := generatePassword(opts)
+		if err != nil {
+			t.Fatalf("length %d: %v", length, err)
+		}
+		if n := characterCount(password); n != length {
+			t.Errorf("length %d: got %d characters", length, n)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word lists are stored one word per line. The EFF lists (eff.org, CC BY 3.0)
+// keep their dice roll column, so only the last field of each line is used;
+// the other languages are the 2048-word BIP-39 lists.
+//
+//go:embed wordlists/*.txt
+var wordlistFS embed.FS
+
+var passphraseWordlists = map[string]string{
+	"eff-large": "wordlists/eff_large.txt",
+	"eff-short": "wordlists/eff_short.txt",
+	"czech":     "wordlists/czech.txt",
+	"french":    "wordlists/french.txt",
+	"italian":   "wordlists/italian.txt",
+	"spanish":   "wordlists/spanish.txt",
+}
+
+const (
+	capitalizeNone   = "none"
+	capitalizeFirst  = "first"
+	capitalizeUpper  = "upper"
+	capitalizeRandom = "random"
+)
+
+type PassphraseOptions struct {
+	Words      int    `json:"words"`
+	Separator  string `json:"separator"`
+	Wordlist   string `json:"wordlist"`
+	Capitalize string `json:"capitalize"`      // none, first, upper or random
+	Digit      bool   `json:"digit,omitempty"` // Insert one random digit into a random word
+}
+
+func wordlistNames() []string {
+	names := make([]string, 0, len(passphraseWordlists))
+	for name := range passphraseWordlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func loadWordlist(name string) ([]string, error) {
+	path, ok := passphraseWordlists[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown wordlist '%s' (available: %s)", name, strings.Join(wordlistNames(), ", "))
+	}
+
+	file, err := wordlistFS.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist: %v", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			words = append(words, fields[len(fields)-1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wordlist: %v", err)
+	}
+
+	return words, nil
+}
+
+func capitalizeWord(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// passphraseEntropy returns the entropy in bits of a passphrase generated
+// with opts from a list of listSize words.
+func passphraseEntropy(opts PassphraseOptions, listSize int) float64 {
+	bits := float64(opts.Words) * math.Log2(float64(listSize))
+	if opts.Capitalize == capitalizeRandom {
+		bits += float64(opts.Words)
+	}
+	if opts.Digit {
+		bits += math.Log2(10 * float64(opts.Words))
+	}
+	return bits
+}
+
+func (opts PassphraseOptions) config() string {
+	parts := []string{
+		fmt.Sprintf("words=%d", opts.Words),
+		fmt.Sprintf("wordlist=%s", opts.Wordlist),
+		fmt.Sprintf("separator=%q", opts.Separator),
+		fmt.Sprintf("capitalize=%s", opts.Capitalize),
+	}
+	if opts.Digit {
+		parts = append(parts, "digit")
+	}
+	return fmt.Sprintf("passphrase(%s)", strings.Join(parts, ", "))
+}
+
+func generatePassphrase(opts PassphraseOptions) (string, float64, error) {
+	if opts.Words <= 0 {
+		return "", 0, fmt.Errorf("word count must be greater than 0")
+	}
+
+	words, err := loadWordlist(opts.Wordlist)
+	if err != nil {
+		return "", 0, err
+	}
+
+	switch opts.Capitalize {
+	case capitalizeNone, capitalizeFirst, capitalizeUpper, capitalizeRandom:
+	default:
+		return "", 0, fmt.Errorf("unknown capitalization '%s' (expected none, first, upper or random)", opts.Capitalize)
+	}
+
+	chosen := make([]string, opts.Words)
+	for i := range chosen {
+		index, err := randomInt(len(words))
+		if err != nil {
+			return "", 0, err
+		}
+		word := words[index]
+
+		switch opts.Capitalize {
+		case capitalizeFirst:
+			word = capitalizeWord(word)
+		case capitalizeUpper:
+			word = strings.ToUpper(word)
+		case capitalizeRandom:
+			coin, err := randomInt(2)
+			if err != nil {
+				return "", 0, err
+			}
+			if coin == 1 {
+				word = capitalizeWord(word)
+			}
+		}
+		chosen[i] = word
+	}
+
+	if opts.Digit {
+		wordIndex, err := randomInt(len(chosen))
+		if err != nil {
+			return "", 0, err
+		}
+		digit, err := randomInt(10)
+		if err != nil {
+			return "", 0, err
+		}
+		chosen[wordIndex] += fmt.Sprintf("%d", digit)
+	}
+
+	return strings.Join(chosen, opts.Separator), passphraseEntropy(opts, len(words)), nil
+}
+
+func AddPassphrase(name, account string, opts PassphraseOptions) error {
+	return AddPasswordWithPolicy(name, account, GeneratorPolicy{Passphrase: &opts})
+}
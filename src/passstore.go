@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pass(1) keeps one GPG-encrypted file per secret. The first line is the
+// password and the rest is free-form; by convention "login:" and "url:"
+// lines hold the username and site, and pass-otp adds an otpauth:// line.
+const (
+	passStoreGPGID     = ".gpg-id"
+	passStoreExtension = ".gpg"
+	passStoreEscape    = `\` // Prefixes note lines that would otherwise be read as fields
+)
+
+var (
+	passStoreLoginKeys = map[string]bool{"login": true, "username": true, "user": true}
+	passStoreURLKeys   = map[string]bool{"url": true, "website": true}
+)
+
+// passStoreGPG returns the gpg binary, preferring gpg2 like pass does.
+func passStoreGPG() (string, error) {
+	for _, name := range []string{"gpg2", "gpg"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("gpg not found on PATH")
+}
+
+// runGPG runs gpg with the options pass uses, so the agent and its
+// pinentry handle any passphrase.
+func runGPG(gpg string, stdin []byte, args ...string) ([]byte, error) {
+	args = append([]string{"--quiet", "--yes", "--compress-algo=none", "--no-encrypt-to", "--batch", "--use-agent"}, args...)
+	cmd := exec.Command(gpg, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("gpg failed: %s", strings.ReplaceAll(msg, "\n", "; "))
+		}
+		return nil, fmt.Errorf("gpg failed: %v", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// passStoreField reports whether import would read line, after the
+// password, as a login, URL or OTP field rather than a note.
+func passStoreField(line string) bool {
+	key, _, ok := strings.Cut(line, ":")
+	key = strings.ToLower(key)
+	return strings.HasPrefix(line, "otpauth://") || (ok && (passStoreLoginKeys[key] || passStoreURLKeys[key]))
+}
+
+// passStoreNoteLines splits notes into lines for a pass file, escaping
+// those passStoreEntry would otherwise read as fields.
+func passStoreNoteLines(notes string) []string {
+	lines := strings.Split(notes, "\n")
+	for i, line := range lines {
+		if passStoreField(line) || strings.HasPrefix(line, passStoreEscape) {
+			lines[i] = passStoreEscape + line
+		}
+	}
+	return lines
+}
+
+// passStoreEntry maps a decrypted file at dir/service/account.gpg to a
+// password entry and its TOTP field. Files directly in a folder have no
+// account in their path and use the "login:" line instead.
+func passStoreEntry(rel string, content []byte) (PasswordEntry, string) {
+	parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, passStoreExtension)), "/")
+	entry := PasswordEntry{Name: parts[len(parts)-1]}
+	if len(parts) >= 2 {
+		entry.Name, entry.Account = parts[len(parts)-2], parts[len(parts)-1]
+		entry.Group = strings.Join(parts[:len(parts)-2], "/")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	totp := ""
+	var notes []string
+	for i, line := range lines {
+		switch key, value, _ := strings.Cut(line, ":"); {
+		case i > 0 && strings.HasPrefix(line, passStoreEscape):
+			notes = append(notes, strings.TrimPrefix(line, passStoreEscape))
+		case strings.HasPrefix(line, "otpauth://"):
+			if totp == "" {
+				totp = strings.TrimSpace(line)
+			}
+		case i == 0:
+			entry.Password = line
+		case passStoreLoginKeys[strings.ToLower(key)] && entry.Account == "":
+			entry.Account = strings.TrimSpace(value)
+		case passStoreURLKeys[strings.ToLower(key)]:
+			entry.URIs = append(entry.URIs, strings.TrimSpace(value))
+		default:
+			notes = append(notes, line)
+		}
+	}
+	entry.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return entry, totp
+}
+
+// ImportPassStore imports every .gpg file under a password-store
+// directory, decrypting each with the local gpg.
+func ImportPassStore(dir string, dryRun bool) error {
+	gpg, err := passStoreGPG()
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a password-store directory", dir)
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), passStoreExtension) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read password store: %v", err)
+	}
+
+	batch := &importBatch{Source: "pass"}
+	for _, path := range files {
+		rel, _ := filepath.Rel(dir, path)
+		content, err := runGPG(gpg, nil, "--decrypt", path)
+		if err != nil {
+			batch.skip("file", rel, "", err.Error())
+			continue
+		}
+		entry, totp := passStoreEntry(rel, content)
+		batch.addLogin(entry, totp)
+	}
+	return applyImport(batch, dryRun)
+}
+
+// passStoreRecipients returns the GPG IDs for a file in dir, read from the
+// nearest .gpg-id at or above it in the store, as pass does.
+func passStoreRecipients(root, dir string) ([]string, error) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, passStoreGPGID))
+		if err == nil {
+			var ids []string
+			for _, line := range strings.Split(string(data), "\n") {
+				if id := strings.TrimSpace(strings.SplitN(line, "#", 2)[0]); id != "" {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("%s lists no GPG IDs", filepath.Join(dir, passStoreGPGID))
+			}
+			return ids, nil
+		}
+		if dir == root {
+			return nil, fmt.Errorf("no %s in the password store; initialize it with --gpg-id", passStoreGPGID)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// passStoreComponent makes name usable as one path component. Names
+// starting with a dot are escaped too, since pass and import skip hidden
+// directories.
+func passStoreComponent(name string) string {
+	name = strings.ReplaceAll(name, "/", "-")
+	if name == "" || strings.HasPrefix(name, ".") {
+		return "_" + name
+	}
+	return name
+}
+
+// ExportPassStore writes every password, and every MFA secret as an
+// otpauth line, to a password-store directory as group/name/account.gpg,
+// encrypted to the store's .gpg-id. gpgID initializes a new store. Files
+// that already exist are left alone, and entries whose path another entry
+// already took are reported. Note lines that import would read as fields
+// are prefixed with a backslash.
+func ExportPassStore(dir, gpgID string) error {
+	gpg, err := passStoreGPG()
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(filepath.Join(root, passStoreGPGID))
+	switch {
+	case gpgID != "" && err == nil && strings.TrimSpace(string(existing)) != gpgID:
+		return fmt.Errorf("the store is already initialized for %s", strings.Join(strings.Fields(string(existing)), ", "))
+	case gpgID != "" && os.IsNotExist(err):
+		if err := os.MkdirAll(root, 0700); err != nil {
+			return fmt.Errorf("failed to create password store: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, passStoreGPGID), []byte(gpgID+"\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %v", passStoreGPGID, err)
+		}
+	}
+
+	passwords, err := loadPasswordStorage()
+	if err != nil {
+		return fmt.Errorf("error loading passwords: %v", err)
+	}
+	mfa, err := loadMFAStorage()
+	if err != nil {
+		return fmt.Errorf("error loading MFA entries: %v", err)
+	}
+
+	files := make(map[string][]string)
+	var collisions []string
+	addFile := func(rel, label string, lines []string) {
+		if _, ok := files[rel]; ok {
+			collisions = append(collisions, fmt.Sprintf("%s (%s is already used)", label, rel))
+			return
+		}
+		files[rel] = lines
+	}
+	usedMFA := make(map[int]bool)
+	for _, p := range passwords.Entries {
+		lines := []string{p.Password}
+		for _, uri := range p.URIs {
+			lines = append(lines, "url: "+uri)
+		}
+		if p.Notes != "" {
+			lines = append(lines, passStoreNoteLines(p.Notes)...)
+		}
+		for i, m := range mfa.Entries {
+			if !usedMFA[i] && m.Account == p.Name && m.Name == p.Account {
+				lines = append(lines, mfaEntryURI(m))
+				usedMFA[i] = true
+				break
+			}
+		}
+
+		var parts []string
+		for _, group := range strings.Split(p.Group, "/") {
+			if group != "" {
+				parts = append(parts, passStoreComponent(group))
+			}
+		}
+		parts = append(parts, passStoreComponent(p.Name), passStoreComponent(p.Account))
+		addFile(filepath.Join(parts...), describeEntry(p.Name, p.Account), lines)
+	}
+	for i, m := range mfa.Entries {
+		if !usedMFA[i] {
+			addFile(filepath.Join(passStoreComponent(m.Account), passStoreComponent(m.Name)), "MFA "+describeEntry(m.Account, m.Name), []string{mfaEntryURI(m)})
+		}
+	}
+
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var skipped []string
+	written := 0
+	for _, rel := range paths {
+		path := filepath.Join(root, rel+passStoreExtension)
+		if _, err := os.Stat(path); err == nil {
+			skipped = append(skipped, rel)
+			continue
+		}
+		// Resolve the recipients first so a store without a .gpg-id is
+		// left without empty directories.
+		recipients, err := passStoreRecipients(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+
+		args := []string{"--encrypt", "--output", path}
+		for _, id := range recipients {
+			args = append(args, "--recipient", id)
+		}
+		content := strings.Join(files[rel], "\n") + "\n"
+		if _, err := runGPG(gpg, []byte(content), args...); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %v", rel, err)
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			return err
+		}
+		written++
+	}
+
+	fmt.Printf("Exported %d password(s) and %d MFA entry(ies) to %s: wrote %d file(s)\n",
+		len(passwords.Entries), len(mfa.Entries), root, written)
+	if len(skipped) > 0 {
+		fmt.Printf("Skipped %d existing file(s):\n", len(skipped))
+		for _, rel := range skipped {
+			fmt.Printf("  %s\n", rel)
+		}
+	}
+	if len(collisions) > 0 {
+		fmt.Println("Not exported, their path is taken by an earlier entry:")
+		for _, c := range collisions {
+			fmt.Printf("  %s\n", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPassStoreNotesRoundTrip(t *testing.T) {
+	notes := strings.Join([]string{
+		"url: not a URI",
+		"Login: written in the notes",
+		"user:x",
+		"website: example.org",
+		"otpauth://totp/not-a-secret",
+		`\already escaped`,
+		"plain note: with a colon",
+	}, "\n")
+
+	lines := append([]string{"hunter2"}, passStoreNoteLines(notes)...)
+	content := strings.Join(lines, "\n") + "\n"
+
+	entry, totp := passStoreEntry("example.com/alice.gpg", []byte(content))
+	if entry.Password != "hunter2" || entry.Account != "alice" {
+		t.Errorf("got password %q, account %q", entry.Password, entry.Account)
+	}
+	if len(entry.URIs) != 0 || totp != "" {
+		t.Errorf("note lines read as fields: URIs %q, TOTP %q", entry.URIs, totp)
+	}
+	if entry.Notes != notes {
+		t.Errorf("notes = %q, want %q", entry.Notes, notes)
+	}
+}
+
+func TestPassStoreComponent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"example.com", "example.com"},
+		{"a/b", "a-b"},
+		{"", "_"},
+		{".", "_."},
+		{"..", "_.."},
+		{".ssh", "_.ssh"},
+		{"..hidden", "_..hidden"},
+		{"/etc", "-etc"},
+	}
+	for _, tt := range tests {
+		if got := passStoreComponent(tt.name); got != tt.want {
+			t.Errorf("passStoreComponent(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportPassStoreWithoutGPGID(t *testing.T) {
+	if _, err := passStoreGPG(); err != nil {
+		t.Skip(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	entry := PasswordEntry{Name: "example.com", Account: "alice", Password: "hunter2", Group: "Internet/Social"}
+	if err := savePasswordStorage(&PasswordStorage{Entries: []PasswordEntry{entry}}); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := ExportPassStore(root, ""); err == nil {
+		t.Fatal("export to a store without a .gpg-id succeeded")
+	}
+	left, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range left {
+		t.Errorf("left behind %s", filepath.Join(root, e.Name()))
+	}
+}
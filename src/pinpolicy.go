@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed pindata/common_pins.txt
+var pinDataFS embed.FS
+
+// maxPINAttempts bounds how often a PIN is regenerated to satisfy a policy.
+const maxPINAttempts = 10000
+
+// PINPolicy lists the weak-PIN checks an entry opts out of. The zero value
+// enforces every check.
+type PINPolicy struct {
+	AllowRepeats   bool `json:"allow_repeats,omitempty"`
+	AllowSequences bool `json:"allow_sequences,omitempty"`
+	AllowCommon    bool `json:"allow_common,omitempty"`
+	AllowDates     bool `json:"allow_dates,omitempty"`
+	AllowReuse     bool `json:"allow_reuse,omitempty"`
+}
+
+var pinPolicyChecks = []string{"repeats", "sequences", "common", "dates", "reuse"}
+
+// parsePINPolicy parses the comma-separated --allow list. strict asks for
+// every check, so it can't be combined with an --allow list.
+func parsePINPolicy(allow string, strict bool) (PINPolicy, error) {
+	var policy PINPolicy
+	if strict && allow != "" {
+		return PINPolicy{}, fmt.Errorf("--strict and --allow cannot be used together")
+	}
+	for _, name := range strings.Split(allow, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "repeats":
+			policy.AllowRepeats = true
+		case "sequences":
+			policy.AllowSequences = true
+		case "common":
+			policy.AllowCommon = true
+		case "dates":
+			policy.AllowDates = true
+		case "reuse":
+			policy.AllowReuse = true
+		case "all":
+			policy = PINPolicy{true, true, true, true, true}
+		default:
+			return PINPolicy{}, fmt.Errorf("unknown PIN check '%s' (use %s or all)", name, strings.Join(pinPolicyChecks, ", "))
+		}
+	}
+	return policy, nil
+}
+
+func (p PINPolicy) String() string {
+	var allowed []string
+	for i, allow := range []bool{p.AllowRepeats, p.AllowSequences, p.AllowCommon, p.AllowDates, p.AllowReuse} {
+		if allow {
+			allowed = append(allowed, pinPolicyChecks[i])
+		}
+	}
+	if len(allowed) == 0 {
+		return "strict"
+	}
+	return "allows " + strings.Join(allowed, ", ")
+}
+
+var commonPINs = sync.OnceValue(func() map[string]bool {
+	pins := make(map[string]bool)
+	file, err := pinDataFS.Open("pindata/common_pins.txt")
+	if err != nil {
+		return pins
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			pins[line] = true
+		}
+	}
+	return pins
+})
+
+// hasRepeats reports PINs made of one repeated unit ("0000", "1212",
+// "123123") or containing three identical digits in a row.
+func hasRepeats(pin string) bool {
+	for unit := 1; unit <= len(pin)/2; unit++ {
+		if len(pin)%unit == 0 && strings.Repeat(pin[:unit], len(pin)/unit) == pin {
+			return true
+		}
+	}
+	for i := 2; i < len(pin); i++ {
+		if pin[i] == pin[i-1] && pin[i] == pin[i-2] {
+			return true
+		}
+	}
+	return false
+}
+
+// isSequence reports PINs whose digits step by a constant amount, wrapping
+// around after 9: "1234", "9876", "7890", "2468".
+func isSequence(pin string) bool {
+	if len(pin) < 3 {
+		return false
+	}
+	step := (int(pin[1]) - int(pin[0]) + 10) % 10
+	for i := 2; i < len(pin); i++ {
+		if (int(pin[i])-int(pin[i-1])+10)%10 != step {
+			return false
+		}
+	}
+	return true
+}
+
+var daysInMonth = []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+func validDayMonth(day, month int) bool {
+	return month >= 1 && month <= 12 && day >= 1 && day <= daysInMonth[month-1]
+}
+
+// looksLikeDate reports PINs that read as a birth year (19xx, 20xx) or as a
+// day and month with an optional year in the common orders.
+func looksLikeDate(pin string) bool {
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	plausibleYear := func(year int) bool {
+		return year >= 1900 && year <= referenceYear()+1
+	}
+
+	switch len(pin) {
+	case 4:
+		return plausibleYear(num(pin)) ||
+			validDayMonth(num(pin[:2]), num(pin[2:])) || // DDMM
+			validDayMonth(num(pin[2:]), num(pin[:2])) // MMDD
+	case 6:
+		return validDayMonth(num(pin[:2]), num(pin[2:4])) || // DDMMYY
+			validDayMonth(num(pin[2:4]), num(pin[:2])) || // MMDDYY
+			validDayMonth(num(pin[4:]), num(pin[2:4])) // YYMMDD
+	case 8:
+		return (validDayMonth(num(pin[:2]), num(pin[2:4])) && plausibleYear(num(pin[4:]))) || // DDMMYYYY
+			(validDayMonth(num(pin[2:4]), num(pin[:2])) && plausibleYear(num(pin[4:]))) || // MMDDYYYY
+			(plausibleYear(num(pin[:4])) && validDayMonth(num(pin[6:]), num(pin[4:6]))) // YYYYMMDD
+	}
+	return false
+}
+
+// pinPolicyViolation returns why pin breaks policy, or "" if it does not.
+// otherPINs are the PINs of the other stored entries.
+func pinPolicyViolation(pin string, policy PINPolicy, otherPINs []string) string {
+	switch {
+	case !policy.AllowRepeats && hasRepeats(pin):
+		return "repeated digits"
+	case !policy.AllowSequences && isSequence(pin):
+		return "digits form a sequence"
+	case !policy.AllowCommon && commonPINs()[pin]:
+		return "one of the most common PINs"
+	case !policy.AllowDates && looksLikeDate(pin):
+		return "looks like a date or year"
+	}
+	if !policy.AllowReuse {
+		for _, other := range otherPINs {
+			if other == pin {
+				return "already used by another entry"
+			}
+		}
+	}
+	return ""
+}
+
+// weakPINReason describes why pin is trivially guessable, or returns "" if
+// it is not.
+func weakPINReason(pin string) string {
+	if len(pin) < 4 {
+		return "shorter than 4 digits"
+	}
+	return pinPolicyViolation(pin, PINPolicy{}, nil)
+}
+
+// generatePINWithPolicy draws random PINs until one satisfies policy.
+func generatePINWithPolicy(length int, policy PINPolicy, otherPINs []string) (string, error) {
+	for attempt := 0; attempt < maxPINAttempts; attempt++ {
+		pin, err := generateMPIN(length)
+		if err != nil {
+			return "", err
+		}
+		if pinPolicyViolation(pin, policy, otherPINs) == "" {
+			return pin, nil
+		}
+	}
+	return "", fmt.Errorf("no %d-digit PIN satisfies the policy (%s); relax it with --allow", length, policy)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// GeneratorPolicy is a reusable set of generator settings. Exactly one of
+// Password, Passphrase, Pronounceable and Secret is set.
+type GeneratorPolicy struct {
+	Name          string                `json:"name,omitempty"`
+	Password      *PasswordOptions      `json:"password,omitempty"`
+	Passphrase    *PassphraseOptions    `json:"passphrase,omitempty"`
+	Pronounceable *PronounceableOptions `json:"pronounceable,omitempty"`
+	Secret        *SecretOptions        `json:"secret,omitempty"`
+	MinScore      int                   `json:"min_score,omitempty"` // Minimum estimateStrength score, 0 to 4
+}
+
+type PolicyStorage struct {
+	Default  string            `json:"default,omitempty"`
+	Policies []GeneratorPolicy `json:"policies"`
+}
+
+func getPolicyConfigPath() (string, error) {
+	passwordPath, err := getPasswordConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	// Policies live next to passwords.json so they travel with the vault.
+	return filepath.Join(filepath.Dir(passwordPath), "policies.json"), nil
+}
+
+func loadPolicyStorage() (*PolicyStorage, error) {
+	configPath, err := getPolicyConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	storage := &PolicyStorage{Policies: []GeneratorPolicy{}}
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return storage, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy config file: %v", err)
+	}
+
+	if len(data) == 0 {
+		return storage, nil
+	}
+
+	if err := json.Unmarshal(data, storage); err != nil {
+		return nil, fmt.Errorf("failed to parse policy config file: %v", err)
+	}
+
+	return storage, nil
+}
+
+func savePolicyStorage(storage *PolicyStorage) error {
+	configPath, err := getPolicyConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(storage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy config: %v", err)
+	}
+
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write policy config file: %v", err)
+	}
+
+	return nil
+}
+
+func (p GeneratorPolicy) config() string {
+	config := "none"
+	if p.Passphrase != nil {
+		config = p.Passphrase.config()
+	} else if p.Pronounceable != nil {
+		config = p.Pronounceable.config()
+	} else if p.Password != nil {
+		config = p.Password.config()
+	} else if p.Secret != nil {
+		config = p.Secret.config()
+	}
+	if p.MinScore > 0 {
+		config += fmt.Sprintf(", min-score %d", p.MinScore)
+	}
+	return config
+}
+
+// maxStrengthAttempts bounds how often generate retries to reach MinScore.
+const maxStrengthAttempts = 100
+
+// generate produces a password from the policy along with its entropy in
+// bits. Passwords scoring below MinScore are discarded and regenerated.
+func (p GeneratorPolicy) generate() (string, float64, error) {
+	if p.MinScore < 0 || p.MinScore > 4 {
+		return "", 0, fmt.Errorf("min-score must be between 0 and 4")
+	}
+
+	for attempt := 0; attempt < maxStrengthAttempts; attempt++ {
+		password, entropy, err := p.generateOnce()
+		if err != nil {
+			return "", 0, err
+		}
+		if checkStrength(password, p.MinScore) == nil {
+			return password, entropy, nil
+		}
+	}
+	return "", 0, fmt.Errorf("could not reach strength score %d in %d attempts; allow a longer or larger character set", p.MinScore, maxStrengthAttempts)
+}
+
+func (p GeneratorPolicy) generateOnce() (string, float64, error) {
+	if p.Passphrase != nil {
+		return generatePassphrase(*p.Passphrase)
+	}
+	if p.Pronounceable != nil {
+		return generatePronounceable(*p.Pronounceable)
+	}
+	if p.Password != nil {
+		password, err := generatePassword(*p.Password)
+		if err != nil {
+			return "", 0, err
+		}
+		return password, passwordEntropy(*p.Password), nil
+	}
+	if p.Secret != nil {
+		secret, err := generateSecret(*p.Secret)
+		if err != nil {
+			return "", 0, err
+		}
+		return secret, p.Secret.entropy(), nil
+	}
+	return "", 0, fmt.Errorf("policy '%s' has no generator settings", p.Name)
+}
+
+func findPolicy(storage *PolicyStorage, name string) (*GeneratorPolicy, error) {
+	for i := range storage.Policies {
+		if storage.Policies[i].Name == name {
+			return &storage.Policies[i], nil
+		}
+	}
+	return nil, fmt.Errorf("policy '%s' not found", name)
+}
+
+// GetPolicy returns the named policy, or the configured default policy when
+// name is empty. ok is false if name is empty and no default is set.
+func GetPolicy(name string) (policy GeneratorPolicy, ok bool, err error) {
+	storage, err := loadPolicyStorage()
+	if err != nil {
+		return GeneratorPolicy{}, false, err
+	}
+
+	if name == "" {
+		if storage.Default == "" {
+			return GeneratorPolicy{}, false, nil
+		}
+		name = storage.Default
+	}
+
+	found, err := findPolicy(storage, name)
+	if err != nil {
+		return GeneratorPolicy{}, false, err
+	}
+	return *found, true, nil
+}
+
+// AddPasswordWithPolicy generates a password for name/account from policy
+// and stores it, recording the policy name and rules on the entry.
+func AddPasswordWithPolicy(name, account string, policy GeneratorPolicy) error {
+	if name == "" || account == "" {
+		return fmt.Errorf("name and account are required")
+	}
+
+	password, entropy, err := policy.generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate password: %v", err)
+	}
+
+	now := time.Now()
+	entry := PasswordEntry{
+		Name:      name,
+		Account:   account,
+		Password:  password,
+		Length:    characterCount(password),
+		Config:    policy.config(),
+		Policy:    policy.Name,
+		Generator: &policy,
+		UpdatedAt: &now,
+	}
+	if policy.Password != nil {
+		entry.Rules = policy.Password.Rules
+	}
+
+	if err := storePasswordEntry(entry); err != nil {
+		return err
+	}
+
+	fmt.Printf("Entropy: %.1f bits\n", entropy)
+	strength := estimateStrength(password, name, account)
+	fmt.Printf("Strength: %s\n", strength.summary())
+	if strength.Warning != "" {
+		fmt.Printf("Warning: %s\n", strength.Warning)
+	}
+	return nil
+}
+
+func AddPolicy(policy GeneratorPolicy) error {
+	if policy.Name == "" {
+		return fmt.Errorf("policy name is required")
+	}
+
+	// Generate once so broken settings are rejected before they're saved.
+	if _, _, err := policy.generate(); err != nil {
+		return fmt.Errorf("invalid policy: %v", err)
+	}
+
+	storage, err := loadPolicyStorage()
+	if err != nil {
+		return err
+	}
+
+	if existing, err := findPolicy(storage, policy.Name); err == nil {
+		*existing = policy
+		fmt.Printf("Policy updated: %s (%s)\n", policy.Name, policy.config())
+		return savePolicyStorage(storage)
+	}
+
+	storage.Policies = append(storage.Policies, policy)
+	fmt.Printf("Policy added: %s (%s)\n", policy.Name, policy.config())
+	return savePolicyStorage(storage)
+}
+
+func RemovePolicy(name string) error {
+	storage, err := loadPolicyStorage()
+	if err != nil {
+		return err
+	}
+
+	for i, policy := range storage.Policies {
+		if policy.Name == name {
+			storage.Policies = append(storage.Policies[:i], storage.Policies[i+1:]...)
+			if storage.Default == name {
+				storage.Default = ""
+			}
+			fmt.Printf("Policy removed: %s\n", name)
+			return savePolicyStorage(storage)
+		}
+	}
+
+	return fmt.Errorf("policy '%s' not found", name)
+}
+
+// SetDefaultPolicy makes name the policy add-pass uses when no generator
+// flags are given. An empty name clears the default.
+func SetDefaultPolicy(name string) error {
+	storage, err := loadPolicyStorage()
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		if _, err := findPolicy(storage, name); err != nil {
+			return err
+		}
+	}
+
+	storage.Default = name
+	if name == "" {
+		fmt.Println("Default policy cleared")
+	} else {
+		fmt.Printf("Default policy set to %s\n", name)
+	}
+	return savePolicyStorage(storage)
+}
+
+func ListPolicies() error {
+	storage, err := loadPolicyStorage()
+	if err != nil {
+		return err
+	}
+
+	if len(storage.Policies) == 0 {
+		fmt.Println("No policies found")
+		return nil
+	}
+
+	fmt.Println("Generator Policies:")
+	for _, policy := range storage.Policies {
+		marker := ""
+		if policy.Name == storage.Default {
+			marker = " (default)"
+		}
+		fmt.Printf("  %s%s: %s\n", policy.Name, marker, policy.config())
+	}
+
+	return nil
+}
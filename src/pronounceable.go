@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Pronounceable passwords are built from onset+vowel syllables. Onsets are
+// consonant runs and vowels are vowel runs, so a password splits back into
+// its syllables in exactly one way and the entropy below is exact.
+var (
+	syllableOnsets = []string{
+		"b", "c", "d", "f", "g", "h", "j", "k", "l", "m", "n", "p", "r", "s", "t", "v", "w", "z",
+		"bl", "br", "ch", "cl", "cr", "dr", "fl", "fr", "gl", "gr", "kr", "pl", "pr",
+		"sh", "sk", "sl", "sm", "sn", "sp", "st", "str", "th", "tr",
+	}
+	syllableVowels = []string{"a", "e", "i", "o", "u", "ai", "au", "ea", "ee", "oo", "ou"}
+)
+
+type PronounceableOptions struct {
+	Syllables  int    `json:"syllables"`
+	Capitalize string `json:"capitalize"`      // none, first, upper or random (per syllable)
+	Digit      bool   `json:"digit,omitempty"` // Append one random digit
+}
+
+func (opts PronounceableOptions) config() string {
+	parts := []string{
+		fmt.Sprintf("syllables=%d", opts.Syllables),
+		fmt.Sprintf("capitalize=%s", opts.Capitalize),
+	}
+	if opts.Digit {
+		parts = append(parts, "digit")
+	}
+	return fmt.Sprintf("pronounceable(%s)", strings.Join(parts, ", "))
+}
+
+// pronounceableEntropy returns the entropy in bits of a password generated
+// with opts.
+func pronounceableEntropy(opts PronounceableOptions) float64 {
+	perSyllable := math.Log2(float64(len(syllableOnsets) * len(syllableVowels)))
+	if opts.Capitalize == capitalizeRandom {
+		perSyllable++
+	}
+	bits := float64(opts.Syllables) * perSyllable
+	if opts.Digit {
+		bits += math.Log2(10)
+	}
+	return bits
+}
+
+func generatePronounceable(opts PronounceableOptions) (string, float64, error) {
+	if opts.Syllables <= 0 {
+		return "", 0, fmt.Errorf("syllable count must be greater than 0")
+	}
+
+	switch opts.Capitalize {
+	case capitalizeNone, capitalizeFirst, capitalizeUpper, capitalizeRandom:
+	default:
+		return "", 0, fmt.Errorf("unknown capitalization '%s' (expected none, first, upper or random)", opts.Capitalize)
+	}
+
+	var password strings.Builder
+	for i := 0; i < opts.Syllables; i++ {
+		onset, err := randomInt(len(syllableOnsets))
+		if err != nil {
+			return "", 0, err
+		}
+		vowel, err := randomInt(len(syllableVowels))
+		if err != nil {
+			return "", 0, err
+		}
+		syllable := syllableOnsets[onset] + syllableVowels[vowel]
+
+		switch opts.Capitalize {
+		case capitalizeFirst:
+			if i == 0 {
+				syllable = capitalizeWord(syllable)
+			}
+		case capitalizeUpper:
+			syllable = strings.ToUpper(syllable)
+		case capitalizeRandom:
+			coin, err := randomInt(2)
+			if err != nil {
+				return "", 0, err
+			}
+			if coin == 1 {
+				syllable = capitalizeWord(syllable)
+			}
+		}
+		password.WriteString(syllable)
+	}
+
+	if opts.Digit {
+		digit, err := randomInt(10)
+		if err != nil {
+			return "", 0, err
+		}
+		fmt.Fprintf(&password, "%d", digit)
+	}
+
+	return password.String(), pronounceableEntropy(opts), nil
+}
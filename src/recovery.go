@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// recoveryWarnThreshold is the number of unused recovery codes at or below
+// which listings warn that the account is about to run out.
+const recoveryWarnThreshold = 2
+
+type RecoveryCode struct {
+	Code   string     `json:"code"`
+	UsedAt *time.Time `json:"used_at,omitempty"`
+}
+
+func unusedRecoveryCodes(entry MFAEntry) int {
+	count := 0
+	for _, rc := range entry.RecoveryCodes {
+		if rc.UsedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// recoveryWarning returns a warning line when an entry that has recovery
+// codes is down to its last few, or "" otherwise.
+func recoveryWarning(entry MFAEntry) string {
+	if len(entry.RecoveryCodes) == 0 {
+		return ""
+	}
+
+	unused := unusedRecoveryCodes(entry)
+	if unused == 0 {
+		return fmt.Sprintf("Warning: no unused recovery codes left for %s (%s)", entry.Name, entry.Account)
+	}
+	if unused <= recoveryWarnThreshold {
+		return fmt.Sprintf("Warning: only %d unused recovery codes left for %s (%s)", unused, entry.Name, entry.Account)
+	}
+	return ""
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+// parseRecoveryCodes splits codes separated by commas or newlines, as
+// services usually present them.
+func parseRecoveryCodes(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+
+	var codes []string
+	for _, field := range fields {
+		code := strings.TrimSpace(field)
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func AddRecoveryCodes(account, name string, codes []string) error {
+	if len(codes) == 0 {
+		return fmt.Errorf("no recovery codes provided")
+	}
+
+	unlock, err := lockMFAStorage()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return err
+	}
+
+	entry, err := findMFAEntry(storage, account, name)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for _, rc := range entry.RecoveryCodes {
+		existing[normalizeRecoveryCode(rc.Code)] = true
+	}
+
+	added := 0
+	for _, code := range codes {
+		key := normalizeRecoveryCode(code)
+		if existing[key] {
+			fmt.Printf("Skipping duplicate recovery code: %s\n", code)
+			continue
+		}
+		existing[key] = true
+		entry.RecoveryCodes = append(entry.RecoveryCodes, RecoveryCode{Code: code})
+		added++
+	}
+
+	if err := saveMFAStorage(storage); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d recovery codes for %s (%s), %d unused\n", added, name, account, unusedRecoveryCodes(*entry))
+	return nil
+}
+
+func UseRecoveryCode(account, name, code string) error {
+	unlock, err := lockMFAStorage()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return err
+	}
+
+	entry, err := findMFAEntry(storage, account, name)
+	if err != nil {
+		return err
+	}
+
+	key := normalizeRecoveryCode(code)
+	for i := range entry.RecoveryCodes {
+		rc := &entry.RecoveryCodes[i]
+		if normalizeRecoveryCode(rc.Code) != key {
+			continue
+		}
+
+		if rc.UsedAt != nil {
+			return fmt.Errorf("recovery code was already used on %s", rc.UsedAt.Format("2006-01-02 15:04:05"))
+		}
+
+		now := time.Now()
+		rc.UsedAt = &now
+		if err := saveMFAStorage(storage); err != nil {
+			return err
+		}
+
+		fmt.Printf("Recovery code marked as used for %s (%s), %d unused remaining\n", name, account, unusedRecoveryCodes(*entry))
+		if warning := recoveryWarning(*entry); warning != "" {
+			fmt.Println(warning)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("recovery code not found for account '%s' and name '%s'", account, name)
+}
+
+func ListRecoveryCodes(account, name string) error {
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return err
+	}
+
+	entry, err := findMFAEntry(storage, account, name)
+	if err != nil {
+		return err
+	}
+
+	if len(entry.RecoveryCodes) == 0 {
+		fmt.Printf("No recovery codes stored for %s (%s)\n", name, account)
+		return nil
+	}
+
+	fmt.Printf("Recovery codes for %s (%s):\n", name, account)
+	for _, rc := range entry.RecoveryCodes {
+		if rc.UsedAt != nil {
+			fmt.Printf("  %s  (used %s)\n", rc.Code, rc.UsedAt.Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("  %s\n", rc.Code)
+		}
+	}
+	fmt.Printf("%d of %d unused\n", unusedRecoveryCodes(*entry), len(entry.RecoveryCodes))
+
+	if warning := recoveryWarning(*entry); warning != "" {
+		fmt.Println(warning)
+	}
+	return nil
+}
+
+func readRecoveryCodesFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recovery codes file: %v", err)
+	}
+	return parseRecoveryCodes(string(data)), nil
+}
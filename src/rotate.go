@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseAge parses ages such as "90d", "12w" or any time.ParseDuration value.
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+
+	unit := value[len(value)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s'", value)
+		}
+		days := n
+		if unit == 'w' {
+			days *= 7
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age '%s' (use e.g. 90d, 12w or 720h)", value)
+	}
+	return age, nil
+}
+
+// legacyGenerator reconstructs generator settings from the free-form Config
+// string written before settings were stored on the entry, e.g.
+// "lowercase, digits, special(!@#$), no-ambiguous, special 1-2" or
+// "passphrase(words=6, wordlist=eff-large, ...)".
+func legacyGenerator(entry PasswordEntry) (GeneratorPolicy, error) {
+	if strings.HasPrefix(entry.Config, "passphrase(") {
+		return legacyPassphrase(entry.Config)
+	}
+
+	config := entry.Config
+	opts := PasswordOptions{Length: entry.Length}
+	var suffix []string
+
+	// Special characters may themselves contain ", " or ")". Only limits
+	// and flags were ever written after them, so the closing parenthesis is
+	// the last one followed by a parsable remainder.
+	if start := strings.Index(config, "special("); start >= 0 {
+		body := config[start+len("special("):]
+		found := false
+		for end := strings.LastIndex(body, ")"); end >= 0; end = strings.LastIndex(body[:end], ")") {
+			rest := strings.TrimPrefix(body[end+1:], ",")
+			tokens := splitConfig(rest)
+			if applyConfigTokens(&PasswordOptions{}, tokens) == nil {
+				opts.SpecialChars = body[:end]
+				suffix = tokens
+				found = true
+				break
+			}
+		}
+		if !found {
+			return GeneratorPolicy{}, fmt.Errorf("cannot parse config '%s'", entry.Config)
+		}
+		config = config[:start]
+	}
+
+	for _, part := range splitConfig(config) {
+		switch part {
+		case "lowercase":
+			opts.SmallAlpha = true
+		case "uppercase":
+			opts.LargeAlpha = true
+		case "digits":
+			opts.Digits = true
+		case "all (default)":
+		default:
+			suffix = append(suffix, part)
+		}
+	}
+
+	if err := applyConfigTokens(&opts, suffix); err != nil {
+		return GeneratorPolicy{}, fmt.Errorf("cannot parse config '%s'", entry.Config)
+	}
+	return GeneratorPolicy{Password: &opts}, nil
+}
+
+func splitConfig(config string) []string {
+	var parts []string
+	for _, part := range strings.Split(config, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// applyConfigTokens applies the flag and limit parts of a legacy Config
+// string ("no-ambiguous", "digits 2+", "special 1-2") to opts.
+func applyConfigTokens(opts *PasswordOptions, tokens []string) error {
+	for _, token := range tokens {
+		if token == "no-ambiguous" {
+			opts.NoAmbiguous = true
+			continue
+		}
+
+		class, limit, ok := strings.Cut(token, " ")
+		if !ok {
+			return fmt.Errorf("unknown config part '%s'", token)
+		}
+		if class == "max-consecutive" {
+			n, err := strconv.Atoi(limit)
+			if err != nil {
+				return err
+			}
+			opts.MaxConsecutive = n
+			continue
+		}
+
+		var limits ClassLimits
+		if minText, ok := strings.CutSuffix(limit, "+"); ok {
+			n, err := strconv.Atoi(minText)
+			if err != nil {
+				return err
+			}
+			limits.Min = n
+		} else {
+			minText, maxText, ok := strings.Cut(limit, "-")
+			if !ok {
+				return fmt.Errorf("unknown config part '%s'", token)
+			}
+			var err error
+			if limits.Min, err = strconv.Atoi(minText); err != nil {
+				return err
+			}
+			if limits.Max, err = strconv.Atoi(maxText); err != nil {
+				return err
+			}
+		}
+
+		switch class {
+		case classLower, classUpper, classDigits, classSpecial:
+		default:
+			return fmt.Errorf("unknown config part '%s'", token)
+		}
+		if opts.Limits == nil {
+			opts.Limits = make(map[string]ClassLimits)
+		}
+		opts.Limits[class] = limits
+	}
+	return nil
+}
+
+// legacyPassphrase parses the Config string written by PassphraseOptions.
+func legacyPassphrase(config string) (GeneratorPolicy, error) {
+	body, ok := strings.CutSuffix(strings.TrimPrefix(config, "passphrase("), ")")
+	if !ok {
+		return GeneratorPolicy{}, fmt.Errorf("cannot parse config '%s'", config)
+	}
+
+	opts := PassphraseOptions{Capitalize: capitalizeNone}
+	for body != "" {
+		var key string
+		key, body, _ = strings.Cut(body, "=")
+		key = strings.TrimSpace(key)
+		if key == "digit" {
+			opts.Digit = true
+			continue
+		}
+
+		var value string
+		if strings.HasPrefix(body, `"`) {
+			quoted, err := strconv.QuotedPrefix(body)
+			if err != nil {
+				return GeneratorPolicy{}, fmt.Errorf("cannot parse config '%s'", config)
+			}
+			value, _ = strconv.Unquote(quoted)
+			body = body[len(quoted):]
+		} else {
+			value, body, _ = strings.Cut(body, ",")
+			body = "," + body
+		}
+		body = strings.TrimSpace(strings.TrimPrefix(body, ","))
+
+		switch key {
+		case "words":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return GeneratorPolicy{}, fmt.Errorf("cannot parse config '%s'", config)
+			}
+			opts.Words = n
+		case "wordlist":
+			opts.Wordlist = value
+		case "separator":
+			opts.Separator = value
+		case "capitalize":
+			opts.Capitalize = value
+		default:
+			return GeneratorPolicy{}, fmt.Errorf("cannot parse config '%s'", config)
+		}
+	}
+
+	return GeneratorPolicy{Passphrase: &opts}, nil
+}
+
+// generatorForEntry returns the settings a rotation of entry should use:
+// the stored snapshot if there is one, then its passwordrules string, its
+// named policy, the defaults for imported entries and finally the legacy
+// Config string.
+func generatorForEntry(entry PasswordEntry) (GeneratorPolicy, error) {
+	if entry.Generator != nil {
+		return *entry.Generator, nil
+	}
+
+	if entry.Rules != "" {
+		opts, err := optionsFromRules(entry.Rules, entry.Length)
+		if err != nil {
+			return GeneratorPolicy{}, fmt.Errorf("invalid stored rules: %v", err)
+		}
+		return GeneratorPolicy{Password: &opts}, nil
+	}
+
+	if entry.Policy != "" {
+		policy, _, err := GetPolicy(entry.Policy)
+		if err == nil {
+			return policy, nil
+		}
+	}
+
+	// Imported passwords were not generated here; use what add-pass would.
+	if entry.Source != "" {
+		policy, ok, err := GetPolicy("")
+		if err != nil || ok {
+			return policy, err
+		}
+		return GeneratorPolicy{Password: &PasswordOptions{
+			Length:       max(entry.Length, 16),
+			SmallAlpha:   true,
+			LargeAlpha:   true,
+			Digits:       true,
+			SpecialChars: defaultSpecialChars,
+		}}, nil
+	}
+
+	return legacyGenerator(entry)
+}
+
+func rotateEntry(entry *PasswordEntry, now time.Time) error {
+	generator, err := generatorForEntry(*entry)
+	if err != nil {
+		return err
+	}
+
+	password, _, err := generator.generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate password: %v", err)
+	}
+
+	entry.PendingPassword = password
+	entry.PendingSince = &now
+	if entry.Generator == nil {
+		entry.Generator = &generator
+	}
+	return nil
+}
+
+// RotatePasswords generates new passwords for the selected entries and keeps
+// them as pending until confirmed. With a name and account only that entry
+// is rotated; otherwise every entry last updated more than olderThan ago
+// (or with no recorded update time) is. Entries that already have a pending
+// password are skipped unless force is set, since that password may
+// already be in use on the service.
+func RotatePasswords(name, account string, olderThan time.Duration, force bool) error {
+	storage, err := loadPasswordStorage()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var rotated, skipped, failed []string
+
+	for i := range storage.Entries {
+		entry := &storage.Entries[i]
+		label := fmt.Sprintf("%s (%s)", entry.Name, entry.Account)
+
+		if name != "" {
+			if entry.Name != name || entry.Account != account {
+				continue
+			}
+		} else if entry.UpdatedAt != nil && now.Sub(*entry.UpdatedAt) < olderThan {
+			continue
+		}
+
+		if entry.PendingPassword != "" && !force {
+			since := "an unknown date"
+			if entry.PendingSince != nil {
+				since = entry.PendingSince.Format("2006-01-02")
+			}
+			skipped = append(skipped, fmt.Sprintf("%s, pending since %s", label, since))
+			continue
+		}
+
+		if err := rotateEntry(entry, now); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+
+		age := "age unknown"
+		if entry.UpdatedAt != nil {
+			age = fmt.Sprintf("%d days old", int(now.Sub(*entry.UpdatedAt).Hours()/24))
+		}
+		rotated = append(rotated, fmt.Sprintf("%s, %s: %s", label, age, entry.PendingPassword))
+	}
+
+	if name != "" && len(rotated) == 0 && len(skipped) == 0 && len(failed) == 0 {
+		return fmt.Errorf("password not found for %s (%s)", name, account)
+	}
+
+	if len(rotated) > 0 {
+		if err := savePasswordStorage(storage); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Rotation Summary:")
+	fmt.Println("=================")
+	if len(rotated) == 0 && len(skipped) == 0 && len(failed) == 0 {
+		fmt.Println("No passwords needed rotation")
+	}
+	for _, line := range rotated {
+		fmt.Printf("  Pending: %s\n", line)
+	}
+	for _, line := range skipped {
+		fmt.Printf("  Skipped: %s\n", line)
+	}
+	for _, line := range failed {
+		fmt.Printf("  Failed: %s\n", line)
+	}
+	if len(rotated) > 0 {
+		fmt.Printf("\n%d password(s) pending. Change them on the service, then run rotate --confirm.\n", len(rotated))
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("\n%d password(s) already had a pending password. Confirm or discard it first, or use --force to replace it.\n", len(skipped))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d password(s) could not be rotated", len(failed))
+	}
+	return nil
+}
+
+// FinishRotation promotes (confirm) or drops (discard) pending passwords,
+// for one entry if name is given or for all pending entries otherwise.
+func FinishRotation(name, account string, confirm bool) error {
+	storage, err := loadPasswordStorage()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var changed []string
+
+	for i := range storage.Entries {
+		entry := &storage.Entries[i]
+		if entry.PendingPassword == "" {
+			continue
+		}
+		if name != "" && (entry.Name != name || entry.Account != account) {
+			continue
+		}
+
+		if confirm {
+			fmt.Printf("Password confirmed for %s (%s): %s -> %s\n", entry.Name, entry.Account, entry.Password, entry.PendingPassword)
+			entry.Password = entry.PendingPassword
+			entry.Length = characterCount(entry.Password)
+			entry.UpdatedAt = &now
+		} else {
+			fmt.Printf("Pending password discarded for %s (%s)\n", entry.Name, entry.Account)
+		}
+		entry.PendingPassword = ""
+		entry.PendingSince = nil
+		changed = append(changed, entry.Name)
+	}
+
+	if len(changed) == 0 {
+		if name != "" {
+			return fmt.Errorf("no pending password for %s (%s)", name, account)
+		}
+		fmt.Println("No pending passwords")
+		return nil
+	}
+
+	return savePasswordStorage(storage)
+}
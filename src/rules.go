@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Password rules follow the "passwordrules" format published by Apple and
+// used in HTML's passwordrules attribute, for example:
+//
+//	minlength: 12; maxlength: 20; required: lower; required: upper; allowed: [-_!];
+//
+// https://developer.apple.com/password-rules/
+const (
+	rulesSpecialChars = " -~!@#$%^&*_+=`|(){}[:;\"'<>,.?]"
+)
+
+type passwordRules struct {
+	minLength      int
+	maxLength      int
+	maxConsecutive int
+	required       []string // Each set must contribute at least one character
+	allowed        string
+}
+
+func asciiPrintable() string {
+	var b strings.Builder
+	for c := byte(0x20); c <= 0x7e; c++ {
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// rulesClass returns the characters named by a predefined class.
+func rulesClass(name string) (string, error) {
+	switch name {
+	case "upper":
+		return uppercaseChars, nil
+	case "lower":
+		return lowercaseChars, nil
+	case "digit":
+		return digitChars, nil
+	case "special":
+		return rulesSpecialChars, nil
+	case "ascii-printable", "unicode":
+		// Any character satisfies "unicode", so generating from the
+		// printable ASCII subset is always acceptable.
+		return asciiPrintable(), nil
+	default:
+		return "", fmt.Errorf("unknown character class '%s'", name)
+	}
+}
+
+func unionChars(a, b string) string {
+	result := a
+	for _, r := range b {
+		if !strings.ContainsRune(result, r) {
+			result += string(r)
+		}
+	}
+	return result
+}
+
+// parseRulesClasses parses a comma-separated list of predefined classes and
+// [custom] sets into the union of their characters.
+func parseRulesClasses(value string) (string, error) {
+	var chars string
+	rest := strings.TrimSpace(value)
+
+	for rest != "" {
+		if rest[0] == '[' {
+			// "]" may appear literally as the last character of a set,
+			// so the set ends at the last "]" before the next separator.
+			end := -1
+			for i := 1; i < len(rest); i++ {
+				if rest[i] != ']' {
+					continue
+				}
+				next := strings.TrimSpace(rest[i+1:])
+				if next == "" || next[0] == ',' {
+					end = i
+					break
+				}
+			}
+			if end < 0 {
+				return "", fmt.Errorf("unterminated custom character class in '%s'", value)
+			}
+
+			for _, r := range rest[1:end] {
+				if r < 0x20 || r > 0x7e {
+					return "", fmt.Errorf("custom character classes may only contain printable ASCII")
+				}
+				chars = unionChars(chars, string(r))
+			}
+			rest = strings.TrimPrefix(strings.TrimSpace(rest[end+1:]), ",")
+		} else {
+			name, remainder, _ := strings.Cut(rest, ",")
+			classChars, err := rulesClass(strings.TrimSpace(name))
+			if err != nil {
+				return "", err
+			}
+			chars = unionChars(chars, classChars)
+			rest = remainder
+		}
+
+		rest = strings.TrimSpace(rest)
+	}
+
+	if chars == "" {
+		return "", fmt.Errorf("empty character class list")
+	}
+	return chars, nil
+}
+
+// splitRules splits on ";" outside of [custom] sets.
+func splitRules(rules string) []string {
+	var parts []string
+	inSet := false
+	start := 0
+	for i := 0; i < len(rules); i++ {
+		switch rules[i] {
+		case '[':
+			inSet = true
+		case ']':
+			next := strings.TrimSpace(rules[i+1:])
+			if next == "" || next[0] == ';' || next[0] == ',' {
+				inSet = false
+			}
+		case ';':
+			if !inSet {
+				parts = append(parts, rules[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, rules[start:])
+}
+
+func parsePasswordRules(rules string) (*passwordRules, error) {
+	parsed := &passwordRules{}
+
+	for _, part := range splitRules(rules) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule '%s' (expected name: value)", part)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		switch name {
+		case "minlength", "maxlength", "max-consecutive":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%s must be a positive number, got '%s'", name, value)
+			}
+			switch name {
+			case "minlength":
+				parsed.minLength = max(parsed.minLength, n)
+			case "maxlength":
+				if parsed.maxLength == 0 || n < parsed.maxLength {
+					parsed.maxLength = n
+				}
+			case "max-consecutive":
+				if parsed.maxConsecutive == 0 || n < parsed.maxConsecutive {
+					parsed.maxConsecutive = n
+				}
+			}
+		case "required":
+			chars, err := parseRulesClasses(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid required rule: %v", err)
+			}
+			parsed.required = append(parsed.required, chars)
+		case "allowed":
+			chars, err := parseRulesClasses(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed rule: %v", err)
+			}
+			parsed.allowed = unionChars(parsed.allowed, chars)
+		default:
+			return nil, fmt.Errorf("unknown rule '%s'", name)
+		}
+	}
+
+	return parsed, nil
+}
+
+// optionsFromRules turns a passwordrules string into generator options.
+// preferredLength is used when it falls inside the rules' length range and
+// clamped to the range otherwise.
+func optionsFromRules(rules string, preferredLength int) (PasswordOptions, error) {
+	parsed, err := parsePasswordRules(rules)
+	if err != nil {
+		return PasswordOptions{}, err
+	}
+
+	if parsed.maxLength > 0 && parsed.minLength > parsed.maxLength {
+		return PasswordOptions{}, fmt.Errorf("minlength %d is greater than maxlength %d", parsed.minLength, parsed.maxLength)
+	}
+
+	// With no allowed or required rules every printable character is fine.
+	charset := parsed.allowed
+	for _, set := range parsed.required {
+		charset = unionChars(charset, set)
+	}
+	if charset == "" {
+		charset = asciiPrintable()
+	}
+
+	length := max(preferredLength, parsed.minLength)
+	if parsed.maxLength > 0 {
+		length = min(length, parsed.maxLength)
+	}
+	if len(parsed.required) > length {
+		return PasswordOptions{}, fmt.Errorf("%d required character classes cannot fit in %d characters", len(parsed.required), length)
+	}
+	if parsed.maxConsecutive == 1 && len(charset) == 1 && length > 1 {
+		return PasswordOptions{}, fmt.Errorf("a single allowed character cannot satisfy max-consecutive: 1")
+	}
+
+	opts := PasswordOptions{
+		Length:         length,
+		Limits:         make(map[string]ClassLimits),
+		MaxConsecutive: parsed.maxConsecutive,
+		Rules:          strings.TrimSpace(rules),
+	}
+
+	// Whole standard classes map onto the usual flags; whatever is left
+	// of the charset becomes the special characters.
+	containsAll := func(chars string) bool {
+		for _, r := range chars {
+			if !strings.ContainsRune(charset, r) {
+				return false
+			}
+		}
+		return true
+	}
+	remaining := charset
+	if containsAll(lowercaseChars) {
+		opts.SmallAlpha = true
+		remaining = removeChars(remaining, lowercaseChars)
+	}
+	if containsAll(uppercaseChars) {
+		opts.LargeAlpha = true
+		remaining = removeChars(remaining, uppercaseChars)
+	}
+	if containsAll(digitChars) {
+		opts.Digits = true
+		remaining = removeChars(remaining, digitChars)
+	}
+	opts.SpecialChars = remaining
+
+	// Rules only require what they list, so no class gets an implicit
+	// minimum; a required set matching a standard class exactly becomes
+	// that class's minimum instead of an extra set.
+	classChars := map[string]string{classLower: lowercaseChars, classUpper: uppercaseChars, classDigits: digitChars, classSpecial: remaining}
+	for name := range classChars {
+		opts.Limits[name] = ClassLimits{}
+	}
+	for _, set := range parsed.required {
+		matched := false
+		for name, chars := range classChars {
+			if chars != "" && removeChars(chars, set) == "" && removeChars(set, chars) == "" {
+				if opts.Limits[name].Min == 0 {
+					opts.Limits[name] = ClassLimits{Min: 1}
+					matched = true
+				}
+				break
+			}
+		}
+		if !matched {
+			opts.RequiredSets = append(opts.RequiredSets, set)
+		}
+	}
+
+	// Drop the zero entries again so the stored config stays readable.
+	for name, limits := range opts.Limits {
+		if limits == (ClassLimits{}) && !classSelected(opts, name) {
+			delete(opts.Limits, name)
+		}
+	}
+
+	return opts, nil
+}
+
+func classSelected(opts PasswordOptions, name string) bool {
+	switch name {
+	case classLower:
+		return opts.SmallAlpha
+	case classUpper:
+		return opts.LargeAlpha
+	case classDigits:
+		return opts.Digits
+	case classSpecial:
+		return opts.SpecialChars != ""
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Secret kinds produce machine secrets in their canonical encodings rather
+// than human-typeable passwords.
+const (
+	secretKindHex       = "hex"
+	secretKindBase64URL = "base64url"
+	secretKindUUID      = "uuid"
+	secretKindBytes     = "bytes"
+
+	defaultSecretBits = 256
+	maxSecretBits     = 8192
+)
+
+var secretKinds = []string{secretKindHex, secretKindBase64URL, secretKindUUID, secretKindBytes}
+
+// SecretOptions configures generateSecret. Bits is ignored for UUIDs, which
+// always carry 122 random bits.
+type SecretOptions struct {
+	Kind string `json:"kind"`
+	Bits int    `json:"bits,omitempty"`
+}
+
+func (opts SecretOptions) validate() error {
+	switch opts.Kind {
+	case secretKindUUID:
+		return nil
+	case secretKindHex, secretKindBase64URL, secretKindBytes:
+	default:
+		return fmt.Errorf("unknown secret kind '%s' (use hex, base64url, uuid or bytes)", opts.Kind)
+	}
+
+	if opts.Bits < 64 || opts.Bits > maxSecretBits || opts.Bits%8 != 0 {
+		return fmt.Errorf("bits must be a multiple of 8 between 64 and %d, got %d", maxSecretBits, opts.Bits)
+	}
+	return nil
+}
+
+func (opts SecretOptions) config() string {
+	if opts.Kind == secretKindUUID {
+		return "secret(kind=uuid)"
+	}
+	return fmt.Sprintf("secret(kind=%s, bits=%d)", opts.Kind, opts.Bits)
+}
+
+func (opts SecretOptions) entropy() float64 {
+	if opts.Kind == secretKindUUID {
+		return 122
+	}
+	return float64(opts.Bits)
+}
+
+// formatUUIDv4 turns 16 random bytes into an RFC 9562 version 4 UUID.
+func formatUUIDv4(b []byte) string {
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // Variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// generateSecret returns a random secret in the encoding selected by
+// opts.Kind: lowercase hex, unpadded URL-safe base64, a UUIDv4 or padded
+// standard base64 of the raw bytes (as printed by openssl rand -base64).
+func generateSecret(opts SecretOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	if opts.Kind == secretKindUUID {
+		b, err := randomBytes(16)
+		if err != nil {
+			return "", err
+		}
+		return formatUUIDv4(b), nil
+	}
+
+	b, err := randomBytes(opts.Bits / 8)
+	if err != nil {
+		return "", err
+	}
+
+	switch opts.Kind {
+	case secretKindHex:
+		return hex.EncodeToString(b), nil
+	case secretKindBase64URL:
+		return base64.RawURLEncoding.EncodeToString(b), nil
+	default:
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+}
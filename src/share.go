@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+)
+
+const shareBundleVersion = 1
+
+// shareBundle is the JSON payload of an age-encrypted share bundle.
+type shareBundle struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Passwords []PasswordEntry `json:"passwords,omitempty"`
+	MFA       []MFAEntry      `json:"mfa,omitempty"`
+	PINs      []MPINEntry     `json:"pins,omitempty"`
+}
+
+func parseShareRecipients(to string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, key := range strings.Split(to, ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		recipient, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %s: %v", key, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipient public key given")
+	}
+	return recipients, nil
+}
+
+// ShareEntries writes the password, MFA and PIN records for name (and
+// account, if given) to an age bundle that only the holders of the
+// recipients' identities can open.
+func ShareEntries(name, account, to, outPath string) error {
+	recipients, err := parseShareRecipients(to)
+	if err != nil {
+		return err
+	}
+
+	passwords, err := loadPasswordStorage()
+	if err != nil {
+		return fmt.Errorf("error loading passwords: %v", err)
+	}
+	mfa, err := loadMFAStorage()
+	if err != nil {
+		return fmt.Errorf("error loading MFA entries: %v", err)
+	}
+	pins, err := loadMPINConfig()
+	if err != nil {
+		return fmt.Errorf("error loading MPINs: %v", err)
+	}
+
+	bundle := shareBundle{Version: shareBundleVersion, CreatedAt: time.Now().UTC()}
+	for _, entry := range passwords.Entries {
+		if entry.Name == name && (account == "" || entry.Account == account) {
+			// A pending rotation has not been confirmed with the service yet.
+			entry.PendingPassword, entry.PendingSince = "", nil
+			bundle.Passwords = append(bundle.Passwords, entry)
+		}
+	}
+	for _, entry := range mfa.Entries {
+		if entry.Account == name && (account == "" || entry.Name == account) {
+			entry.LastCounter = nil
+			bundle.MFA = append(bundle.MFA, entry)
+		}
+	}
+	for _, entry := range pins.Entries {
+		if entry.Name == name && (account == "" || entry.Account == account) {
+			bundle.PINs = append(bundle.PINs, entry)
+		}
+	}
+	if len(bundle.Passwords)+len(bundle.MFA)+len(bundle.PINs) == 0 {
+		return fmt.Errorf("no entries found for %s", describeEntry(name, account))
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var encrypted bytes.Buffer
+	w, err := age.Encrypt(&encrypted, recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %v", err)
+	}
+
+	file, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %v", err)
+	}
+	if _, err := file.Write(encrypted.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+
+	fmt.Printf("Shared %d password(s), %d MFA entry(ies) and %d PIN(s) for %s in %s\n",
+		len(bundle.Passwords), len(bundle.MFA), len(bundle.PINs), describeEntry(name, account), outPath)
+	return nil
+}
+
+func describeEntry(name, account string) string {
+	if account == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, account)
+}
+
+// ReceiveBundle decrypts a share bundle with the age identities in
+// identityPath and merges its records, reporting conflicts like an import.
+func ReceiveBundle(path, identityPath string, dryRun bool) error {
+	keys, err := os.Open(identityPath)
+	if err != nil {
+		return fmt.Errorf("failed to read identity: %v", err)
+	}
+	identities, err := age.ParseIdentities(keys)
+	keys.Close()
+	if err != nil {
+		return fmt.Errorf("failed to parse identity: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %v", err)
+	}
+	defer file.Close()
+
+	r, err := age.Decrypt(file, identities...)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt bundle: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt bundle: %v", err)
+	}
+
+	var bundle shareBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("failed to parse bundle: %v", err)
+	}
+	if bundle.Version != shareBundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+	return applyImport(bundleBatch(bundle), dryRun)
+}
+
+// bundleBatch checks the records of a received bundle, which may come from
+// another version of this tool, before they are merged.
+func bundleBatch(bundle shareBundle) *importBatch {
+	batch := &importBatch{Source: "Bundle"}
+	for _, entry := range bundle.Passwords {
+		if entry.Name == "" || entry.Account == "" || entry.Password == "" {
+			batch.skip("password", entry.Name, entry.Account, "incomplete entry")
+			continue
+		}
+		batch.Passwords = append(batch.Passwords, entry)
+	}
+	for _, entry := range bundle.MFA {
+		if key, err := decodeSecret(entry.Secret); err != nil || len(key) == 0 || entry.Account == "" || entry.Name == "" {
+			batch.skip("mfa", entry.Account, entry.Name, "incomplete entry")
+			continue
+		}
+		if entry.Type != "" && entry.Type != mfaTypeTOTP && entry.Type != mfaTypeSteam {
+			batch.skip("mfa", entry.Account, entry.Name, fmt.Sprintf("unknown type '%s'", entry.Type))
+			continue
+		}
+		if entry.Period <= 0 {
+			batch.skip("mfa", entry.Account, entry.Name, fmt.Sprintf("invalid period %d", entry.Period))
+			continue
+		}
+		batch.MFA = append(batch.MFA, entry)
+	}
+	for _, entry := range bundle.PINs {
+		if err := validatePIN(entry.PIN, entry.Kind); err != nil || entry.Name == "" || entry.Account == "" {
+			batch.skip("pin", entry.Name, entry.Account, "incomplete entry")
+			continue
+		}
+		batch.PINs = append(batch.PINs, entry)
+	}
+	return batch
+}
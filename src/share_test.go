@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+func TestShareAndReceiveBundle(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(t.TempDir(), "example.age")
+
+	// Sender.
+	t.Setenv("HOME", t.TempDir())
+	updated := time.Date(2024, 3, 5, 10, 20, 30, 0, time.UTC)
+	counter := int64(12345)
+	password := PasswordEntry{Name: "example.com", Account: "alice", Password: "Tr0ub4dor&3", Length: 11, UpdatedAt: &updated}
+	pending := password
+	pending.PendingPassword, pending.PendingSince = "not confirmed", &updated
+	other := PasswordEntry{Name: "other.example", Account: "alice", Password: "unshared"}
+	if err := savePasswordStorage(&PasswordStorage{Entries: []PasswordEntry{pending, other}}); err != nil {
+		t.Fatal(err)
+	}
+	mfa := MFAEntry{Account: "example.com", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: 30}
+	used := mfa
+	used.LastCounter = &counter
+	if err := saveMFAStorage(&MFAStorage{Entries: []MFAEntry{used}}); err != nil {
+		t.Fatal(err)
+	}
+	pin := MPINEntry{Name: "example.com", Account: "alice", PIN: "4821", Kind: "card"}
+	if err := saveMPINConfig(&MPINConfig{Entries: []MPINEntry{pin}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ShareEntries("example.com", "alice", identity.Recipient().String(), bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recipient.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	identityPath := filepath.Join(home, "key.txt")
+	if err := os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stranger, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	strangerPath := filepath.Join(home, "stranger.txt")
+	if err := os.WriteFile(strangerPath, []byte(stranger.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReceiveBundle(bundlePath, strangerPath, false); err == nil {
+		t.Error("bundle opened with the wrong identity")
+	}
+
+	if err := ReceiveBundle(bundlePath, identityPath, false); err != nil {
+		t.Fatal(err)
+	}
+
+	passwords, err := loadPasswordStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passwords.Entries) != 1 || !reflect.DeepEqual(passwords.Entries[0], password) {
+		t.Errorf("passwords = %+v, want only %+v", passwords.Entries, password)
+	}
+	storedMFA, err := loadMFAStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(storedMFA.Entries, []MFAEntry{mfa}) {
+		t.Errorf("MFA = %+v, want %+v", storedMFA.Entries, mfa)
+	}
+	pins, err := loadMPINConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pins.Entries, []MPINEntry{pin}) {
+		t.Errorf("PINs = %+v, want %+v", pins.Entries, pin)
+	}
+}
+
+func TestBundleBatch(t *testing.T) {
+	valid := MFAEntry{Account: "example.com", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: 30}
+	steam := MFAEntry{Account: "steam", Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Period: steamGuardPeriod, Type: mfaTypeSteam}
+
+	tests := []struct {
+		entry  MFAEntry
+		reason string
+	}{
+		{valid, ""},
+		{steam, ""},
+		{MFAEntry{Account: "a", Name: "b", Secret: "JBSWY3DPEHPK3PXP", Period: 0}, "invalid period 0"},
+		{MFAEntry{Account: "a", Name: "b", Secret: "JBSWY3DPEHPK3PXP", Period: -30}, "invalid period -30"},
+		{MFAEntry{Account: "a", Name: "b", Secret: "JBSWY3DPEHPK3PXP", Period: 30, Type: "hotp"}, "unknown type 'hotp'"},
+		{MFAEntry{Account: "a", Name: "b", Secret: "not base32!", Period: 30}, "incomplete entry"},
+		{MFAEntry{Account: "a", Secret: "JBSWY3DPEHPK3PXP", Period: 30}, "incomplete entry"},
+	}
+	for _, tt := range tests {
+		batch := bundleBatch(shareBundle{Version: shareBundleVersion, MFA: []MFAEntry{tt.entry}})
+		if tt.reason == "" {
+			if len(batch.MFA) != 1 || len(batch.Skipped) != 0 {
+				t.Errorf("%+v: not accepted, skipped %+v", tt.entry, batch.Skipped)
+			}
+			continue
+		}
+		if len(batch.MFA) != 0 || len(batch.Skipped) != 1 || batch.Skipped[0].Detail != tt.reason {
+			t.Errorf("%+v: got skipped %+v, want %q", tt.entry, batch.Skipped, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// Spectre (formerly Master Password, https://spectre.app) derives site
+// passwords from the user's full name, a master password, the site name and
+// a counter, so nothing secret has to be stored or synced. This implements
+// algorithm version 3.
+const (
+	spectreScope           = "com.lyndir.masterpassword"
+	spectreDefaultTemplate = "long"
+)
+
+var spectreTemplates = map[string][]string{
+	"maximum": {"anoxxxxxxxxxxxxxxxxx", "axxxxxxxxxxxxxxxxxno"},
+	"long": {
+		"CvcvnoCvcvCvcv", "CvcvCvcvnoCvcv", "CvcvCvcvCvcvno", "CvccnoCvcvCvcv", "CvccCvcvnoCvcv",
+		"CvccCvcvCvcvno", "CvcvnoCvccCvcv", "CvcvCvccnoCvcv", "CvcvCvccCvcvno", "CvcvnoCvcvCvcc",
+		"CvcvCvcvnoCvcc", "CvcvCvcvCvccno", "CvccnoCvccCvcv", "CvccCvccnoCvcv", "CvccCvccCvcvno",
+		"CvcvnoCvccCvcc", "CvcvCvccnoCvcc", "CvcvCvccCvccno", "CvccnoCvcvCvcc", "CvccCvcvnoCvcc",
+		"CvccCvcvCvccno",
+	},
+	"medium": {"CvcnoCvc", "CvcCvcno"},
+	"short":  {"Cvcn"},
+	"basic":  {"aaanaaan", "aannaaan", "aaannaaa"},
+	"pin":    {"nnnn"},
+	"name":   {"cvccvcvcv"},
+	"phrase": {"cvcc cvc cvccvcv cvc", "cvc cvccvcvcv cvcv", "cv cvccv cvc cvcvccv"},
+}
+
+var spectreCharacters = map[byte]string{
+	'V': "AEIOU",
+	'C': "BCDFGHJKLMNPQRSTVWXYZ",
+	'v': "aeiou",
+	'c': "bcdfghjklmnpqrstvwxyz",
+	'A': "AEIOUBCDFGHJKLMNPQRSTVWXYZ",
+	'a': "AEIOUaeiouBCDFGHJKLMNPQRSTVWXYZbcdfghjklmnpqrstvwxyz",
+	'n': "0123456789",
+	'o': "@&%?,=[]_:-+*$#!'^~;()/.",
+	'x': "AEIOUaeiouBCDFGHJKLMNPQRSTVWXYZbcdfghjklmnpqrstvwxyz0123456789!@#$%^&*()",
+	' ': " ",
+}
+
+// SpectreSite holds the non-secret parameters of a deterministic password.
+type SpectreSite struct {
+	Site     string `json:"site"`
+	Login    string `json:"login,omitempty"`
+	Counter  uint32 `json:"counter"`
+	Template string `json:"template"`
+}
+
+type SpectreStorage struct {
+	FullName string        `json:"full_name,omitempty"`
+	KeyID    string        `json:"key_id,omitempty"` // SHA-256 of the master key, catches mistyped master passwords
+	Sites    []SpectreSite `json:"sites"`
+}
+
+func getSpectreConfigPath() (string, error) {
+	passwordPath, err := getPasswordConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(passwordPath), "spectre.json"), nil
+}
+
+func loadSpectreStorage() (*SpectreStorage, error) {
+	configPath, err := getSpectreConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	storage := &SpectreStorage{Sites: []SpectreSite{}}
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return storage, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spectre config file: %v", err)
+	}
+
+	if len(data) == 0 {
+		return storage, nil
+	}
+
+	if err := json.Unmarshal(data, storage); err != nil {
+		return nil, fmt.Errorf("failed to parse spectre config file: %v", err)
+	}
+
+	return storage, nil
+}
+
+func saveSpectreStorage(storage *SpectreStorage) error {
+	configPath, err := getSpectreConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(storage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal spectre config: %v", err)
+	}
+
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write spectre config file: %v", err)
+	}
+
+	return nil
+}
+
+// appendSpectreString appends s with its length as a 32-bit big-endian
+// prefix, as every Spectre salt does.
+func appendSpectreString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+func spectreMasterKey(fullName, masterPassword string) ([]byte, error) {
+	salt := appendSpectreString([]byte(spectreScope), fullName)
+	key, err := scrypt.Key([]byte(masterPassword), salt, 32768, 8, 2, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive master key: %v", err)
+	}
+	return key, nil
+}
+
+func spectreKeyID(masterKey []byte) string {
+	sum := sha256.Sum256(masterKey)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func spectreSitePassword(masterKey []byte, site string, counter uint32, template string) (string, error) {
+	templates, ok := spectreTemplates[template]
+	if !ok {
+		return "", fmt.Errorf("unknown template '%s' (use %s)", template, strings.Join(spectreTemplateNames(), ", "))
+	}
+	if counter == 0 {
+		return "", fmt.Errorf("counter must be at least 1")
+	}
+
+	salt := appendSpectreString([]byte(spectreScope), site)
+	salt = binary.BigEndian.AppendUint32(salt, counter)
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write(salt)
+	siteKey := mac.Sum(nil)
+
+	pattern := templates[int(siteKey[0])%len(templates)]
+	password := make([]byte, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		chars := spectreCharacters[pattern[i]]
+		password[i] = chars[int(siteKey[i+1])%len(chars)]
+	}
+	return string(password), nil
+}
+
+func spectreTemplateNames() []string {
+	names := make([]string, 0, len(spectreTemplates))
+	for name := range spectreTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// promptSecret reads a secret without echoing it when stdin is a terminal,
+// or a single line otherwise so scripts can pipe it in.
+func promptSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %v", err)
+		}
+		return string(secret), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read input: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// unlockSpectre asks for the master password and checks it against the
+// stored key ID. The first successful unlock records the key ID.
+func unlockSpectre(storage *SpectreStorage) ([]byte, error) {
+	if storage.FullName == "" {
+		return nil, fmt.Errorf("full name not set; pass --full-name the first time")
+	}
+
+	masterPassword, err := promptSecret("Master password: ")
+	if err != nil {
+		return nil, err
+	}
+	if masterPassword == "" {
+		return nil, fmt.Errorf("master password cannot be empty")
+	}
+
+	masterKey, err := spectreMasterKey(storage.FullName, masterPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := spectreKeyID(masterKey)
+	if storage.KeyID != "" && subtle.ConstantTimeCompare([]byte(keyID), []byte(storage.KeyID)) != 1 {
+		return nil, fmt.Errorf("master password does not match the one used for %s", storage.FullName)
+	}
+	storage.KeyID = keyID
+	return masterKey, nil
+}
+
+func findSpectreSite(storage *SpectreStorage, site, login string) (*SpectreSite, error) {
+	var found *SpectreSite
+	for i := range storage.Sites {
+		s := &storage.Sites[i]
+		if s.Site != site || (login != "" && s.Login != login) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("several logins stored for %s; pass --login", site)
+		}
+		found = s
+	}
+	if found == nil {
+		return nil, fmt.Errorf("spectre site not found: %s", site)
+	}
+	return found, nil
+}
+
+// AddSpectreSite stores the parameters for site and prints its password.
+// Adding an existing site/login updates its counter and template.
+func AddSpectreSite(fullName string, site SpectreSite) error {
+	if site.Site == "" {
+		return fmt.Errorf("site is required")
+	}
+	// Check the parameters before asking for the master password.
+	if _, err := spectreSitePassword(nil, site.Site, site.Counter, site.Template); err != nil {
+		return err
+	}
+
+	storage, err := loadSpectreStorage()
+	if err != nil {
+		return err
+	}
+
+	if fullName != "" && fullName != storage.FullName {
+		if storage.FullName != "" {
+			fmt.Printf("Warning: changing full name from %s to %s changes every password\n", storage.FullName, fullName)
+		}
+		storage.FullName = fullName
+		storage.KeyID = ""
+	}
+
+	masterKey, err := unlockSpectre(storage)
+	if err != nil {
+		return err
+	}
+
+	password, err := spectreSitePassword(masterKey, site.Site, site.Counter, site.Template)
+	if err != nil {
+		return err
+	}
+
+	updated := false
+	for i := range storage.Sites {
+		if storage.Sites[i].Site == site.Site && storage.Sites[i].Login == site.Login {
+			storage.Sites[i] = site
+			updated = true
+		}
+	}
+	if !updated {
+		storage.Sites = append(storage.Sites, site)
+	}
+
+	if err := saveSpectreStorage(storage); err != nil {
+		return err
+	}
+
+	action := "added"
+	if updated {
+		action = "updated"
+	}
+	fmt.Printf("Spectre site %s for %s (counter %d, %s): %s\n", action, site.Site, site.Counter, site.Template, password)
+	return nil
+}
+
+// GetSpectrePassword re-derives and prints the password for a stored site.
+func GetSpectrePassword(site, login string) error {
+	storage, err := loadSpectreStorage()
+	if err != nil {
+		return err
+	}
+
+	entry, err := findSpectreSite(storage, site, login)
+	if err != nil {
+		return err
+	}
+
+	firstUnlock := storage.KeyID == ""
+	masterKey, err := unlockSpectre(storage)
+	if err != nil {
+		return err
+	}
+	if firstUnlock {
+		if err := saveSpectreStorage(storage); err != nil {
+			return err
+		}
+	}
+
+	password, err := spectreSitePassword(masterKey, entry.Site, entry.Counter, entry.Template)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Password for %s (%s): %s\n", entry.Site, entry.Login, password)
+	return nil
+}
+
+func RemoveSpectreSite(site, login string) error {
+	storage, err := loadSpectreStorage()
+	if err != nil {
+		return err
+	}
+
+	entry, err := findSpectreSite(storage, site, login)
+	if err != nil {
+		return err
+	}
+
+	for i := range storage.Sites {
+		if &storage.Sites[i] == entry {
+			storage.Sites = append(storage.Sites[:i], storage.Sites[i+1:]...)
+			break
+		}
+	}
+
+	fmt.Printf("Spectre site removed: %s\n", site)
+	return saveSpectreStorage(storage)
+}
+
+func ListSpectreSites() error {
+	storage, err := loadSpectreStorage()
+	if err != nil {
+		return err
+	}
+
+	if len(storage.Sites) == 0 {
+		fmt.Println("No spectre sites found")
+		return nil
+	}
+
+	fmt.Printf("Spectre Sites (%s):\n", storage.FullName)
+	for _, site := range storage.Sites {
+		login := site.Login
+		if login == "" {
+			login = "-"
+		}
+		fmt.Printf("  %s, Login: %s, Counter: %d, Template: %s\n", site.Site, login, site.Counter, site.Template)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+// Vectors from the Master Password algorithm version 3 test suite
+// (mpw_tests.xml), all for "Robert Lee Mitchell" / "banana colored duckling".
+func TestSpectreSitePassword(t *testing.T) {
+	masterKey, err := spectreMasterKey("Robert Lee Mitchell", "banana colored duckling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, want := spectreKeyID(masterKey), "98EEF4D1DF46D849574A82A03C3177056B15DFFCA29BB3899DE4628453675302"; id != want {
+		t.Errorf("key ID = %s, want %s", id, want)
+	}
+
+	tests := []struct {
+		site     string
+		counter  uint32
+		template string
+		want     string
+	}{
+		{"masterpasswordapp.com", 1, "long", "Jejr5[RepuSosp"},
+		{"masterpasswordapp.com", 1, "maximum", "W6@692^B1#&@gVdSdLZ@"},
+		{"masterpasswordapp.com", 1, "medium", "Jej2$Quv"},
+		{"masterpasswordapp.com", 1, "basic", "WAo2xIg6"},
+		{"masterpasswordapp.com", 1, "short", "Jej2"},
+		{"masterpasswordapp.com", 1, "pin", "7662"},
+		{"masterpasswordapp.com", 1, "name", "jejraquvo"},
+		{"masterpasswordapp.com", 1, "phrase", "jejr quv cabsibu tam"},
+		{"masterpasswordapp.com", 4294967295, "long", "XambHoqo6[Peni"},
+		{"twitter.com", 1, "long", "PozoLalv0_Yelo"},
+	}
+
+	for _, tt := range tests {
+		got, err := spectreSitePassword(masterKey, tt.site, tt.counter, tt.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s #%d %s = %q, want %q", tt.site, tt.counter, tt.template, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Steam Guard is ordinary 30-second TOTP whose truncated value is written
+// in this 26-character alphabet instead of decimal digits.
+const (
+	steamGuardAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+	steamGuardDigits   = 5
+	steamGuardPeriod   = 30
+)
+
+func encodeSteamGuard(value uint32) string {
+	code := make([]byte, steamGuardDigits)
+	for i := range code {
+		code[i] = steamGuardAlphabet[value%uint32(len(steamGuardAlphabet))]
+		value /= uint32(len(steamGuardAlphabet))
+	}
+	return string(code)
+}
+
+func steamCodeAt(secret string, t time.Time) (string, int, error) {
+	value, remaining, err := totpValueAt(secret, steamGuardPeriod, t)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return encodeSteamGuard(value), remaining, nil
+}
+
+// normalizeSteamSecret accepts either a base32 secret or the base64
+// shared_secret found in Steam authenticator files, and returns base32 so
+// the entry can be stored and decoded like every other MFA secret. Base32
+// is tried first; base64 is only used when the secret isn't valid base32.
+func normalizeSteamSecret(secret string) (string, error) {
+	secret = strings.TrimSpace(secret)
+
+	cleaned := strings.TrimRight(strings.ToUpper(strings.ReplaceAll(secret, " ", "")), "=")
+	raw := base32.StdEncoding.WithPadding(base32.NoPadding)
+	if key, err := raw.DecodeString(cleaned); err == nil && len(key) > 0 && raw.EncodeToString(key) == cleaned {
+		return base32.StdEncoding.EncodeToString(key), nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return "", fmt.Errorf("invalid Steam shared secret: not valid base32 or base64")
+	}
+	return base32.StdEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestSteamCodeAt(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("superdupersecret"))
+
+	vectors := []struct {
+		timestamp int64
+		code      string
+	}{
+		{3000029, "94R9D"},
+		{3000030, "YRGQJ"},
+	}
+
+	for _, v := range vectors {
+		code, _, err := steamCodeAt(secret, time.Unix(v.timestamp, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("at %d: got %s, want %s", v.timestamp, code, v.code)
+		}
+	}
+}
+
+func TestNormalizeSteamSecret(t *testing.T) {
+	want := base32.StdEncoding.EncodeToString([]byte("superdupersecret"))
+
+	for _, secret := range []string{
+		want,
+		"ON2X AZLS MR2X AZLS ONSW G4TF OQ",
+		"on2xazlsmr2xazlsonswg4tfoq======",
+		"c3VwZXJkdXBlcnNlY3JldA==",
+	} {
+		got, err := normalizeSteamSecret(secret)
+		if err != nil {
+			t.Errorf("%q: %v", secret, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got %s, want %s", secret, got, want)
+		}
+	}
+
+	if _, err := normalizeSteamSecret("not a secret!"); err == nil {
+		t.Error("invalid secret was accepted")
+	}
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength estimation follows zxcvbn (Wheeler, USENIX Security 2016): the
+// password is split into the sequence of known patterns that is cheapest to
+// guess, and the guess count of that sequence determines the score.
+const (
+	bruteforceCardinality        = 10
+	minGuessesBeforeGrowingSeq   = 10000
+	minSubmatchGuessesSingleChar = 10
+	minSubmatchGuessesMultiChar  = 50
+	minYearSpace                 = 20
+)
+
+var strengthScoreLabels = []string{"very weak", "weak", "fair", "strong", "very strong"}
+
+// StrengthResult is the outcome of estimateStrength.
+type StrengthResult struct {
+	Guesses          float64
+	Score            int     // 0 (too guessable) to 4 (very unguessable)
+	CrackTimeOnline  float64 // Seconds at 100 guesses per hour (throttled online attack)
+	CrackTimeOffline float64 // Seconds at 10^4 guesses per second (slow hash, offline)
+	Warning          string
+	Sequence         []*strengthMatch
+}
+
+// estimateStrength estimates how many guesses an attacker would need for
+// password. userInputs (entry name, account, ...) are treated as an extra
+// dictionary since they are the first thing a targeted attacker tries.
+func estimateStrength(password string, userInputs ...string) StrengthResult {
+	inputs := make(map[string]int)
+	for _, input := range userInputs {
+		for _, word := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if _, ok := inputs[word]; !ok {
+				inputs[word] = len(inputs) + 1
+			}
+		}
+	}
+
+	runes := []rune(password)
+	result := mostGuessableSequence(runes, omnimatch(runes, inputs), false)
+
+	strength := StrengthResult{
+		Guesses:          result.guesses,
+		Score:            guessesToScore(result.guesses),
+		CrackTimeOnline:  result.guesses / (100.0 / 3600),
+		CrackTimeOffline: result.guesses / 1e4,
+		Sequence:         result.sequence,
+	}
+	if strength.Score <= 2 {
+		strength.Warning = strengthWarning(result.sequence)
+	}
+	return strength
+}
+
+func (r StrengthResult) summary() string {
+	return fmt.Sprintf("%d/4 (%s), crack time %s offline, %s online",
+		r.Score, strengthScoreLabels[r.Score], displayCrackTime(r.CrackTimeOffline), displayCrackTime(r.CrackTimeOnline))
+}
+
+// checkStrength returns an error if password scores below minScore.
+func checkStrength(password string, minScore int, userInputs ...string) error {
+	strength := estimateStrength(password, userInputs...)
+	if strength.Score >= minScore {
+		return nil
+	}
+	if strength.Warning != "" {
+		return fmt.Errorf("password strength %d/4 is below the required %d/4: %s", strength.Score, minScore, strength.Warning)
+	}
+	return fmt.Errorf("password strength %d/4 is below the required %d/4", strength.Score, minScore)
+}
+
+func guessesToScore(guesses float64) int {
+	// The small delta keeps passwords that sit exactly on a threshold, such
+	// as a 3 digit PIN at 1000 guesses, in the lower bucket.
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func displayCrackTime(seconds float64) string {
+	const (
+		minute  = 60
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+
+	units := []struct {
+		name string
+		size float64
+	}{{"year", year}, {"month", month}, {"day", day}, {"hour", hour}, {"minute", minute}, {"second", 1}}
+
+	if seconds < 1 {
+		return "less than a second"
+	}
+	if seconds >= century {
+		return "centuries"
+	}
+	for _, unit := range units {
+		if seconds >= unit.size {
+			n := math.Round(seconds / unit.size)
+			if n == 1 {
+				return fmt.Sprintf("1 %s", unit.name)
+			}
+			return fmt.Sprintf("%.0f %ss", n, unit.name)
+		}
+	}
+	return "less than a second"
+}
+
+func strengthWarning(sequence []*strengthMatch) string {
+	if len(sequence) == 0 {
+		return "Use a few words, avoid common phrases"
+	}
+
+	// The longest match dominates the estimate, so it gets the warning.
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if m.j-m.i > longest.j-longest.i {
+			longest = m
+		}
+	}
+	single := len(sequence) == 1
+
+	switch longest.pattern {
+	case "dictionary":
+		switch longest.dictName {
+		case "passwords":
+			if single && !longest.l33t && !longest.reversed {
+				switch {
+				case longest.rank <= 10:
+					return "This is a top-10 common password"
+				case longest.rank <= 100:
+					return "This is a top-100 common password"
+				default:
+					return "This is a very common password"
+				}
+			}
+			return "This is similar to a commonly used password"
+		case "english":
+			if single {
+				return "A word by itself is easy to guess"
+			}
+		case "surnames", "male_names", "female_names":
+			if single {
+				return "Names and surnames by themselves are easy to guess"
+			}
+			return "Common names and surnames are easy to guess"
+		case "user_inputs":
+			return "Passwords based on the entry name or account are easy to guess"
+		}
+	case "spatial":
+		if longest.turns == 1 {
+			return "Straight rows of keys are easy to guess"
+		}
+		return "Short keyboard patterns are easy to guess"
+	case "repeat":
+		if len([]rune(longest.baseToken)) == 1 {
+			return `Repeats like "aaa" are easy to guess`
+		}
+		return `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+	case "sequence":
+		return "Sequences like abc or 6543 are easy to guess"
+	case "regex":
+		return "Recent years are easy to guess"
+	case "date":
+		return "Dates are often easy to guess"
+	}
+	return ""
+}
+
+type guessResult struct {
+	guesses  float64
+	sequence []*strengthMatch
+}
+
+// mostGuessableSequence finds the sequence of non-overlapping matches,
+// with bruteforce filling the gaps, that minimises
+//
+//	l! * (product of match guesses) + 10000^(l-1)
+//
+// where l is the number of matches. The factorial accounts for the order
+// of the patterns and the additive term penalises long sequences of tiny
+// matches.
+func mostGuessableSequence(password []rune, matches []*strengthMatch, excludeAdditive bool) guessResult {
+	n := len(password)
+	if n == 0 {
+		return guessResult{guesses: 1}
+	}
+
+	byEnd := make([][]*strengthMatch, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	// For every end position k and sequence length l, the best match ending
+	// at k, the product of guesses so far and the overall guess count.
+	optimalM := make([]map[int]*strengthMatch, n)
+	optimalPi := make([]map[int]float64, n)
+	optimalG := make([]map[int]float64, n)
+	for k := 0; k < n; k++ {
+		optimalM[k] = make(map[int]*strengthMatch)
+		optimalPi[k] = make(map[int]float64)
+		optimalG[k] = make(map[int]float64)
+	}
+
+	update := func(m *strengthMatch, l int) {
+		k := m.j
+		pi := estimateGuesses(m, n)
+		if l > 1 {
+			pi *= optimalPi[m.i-1][l-1]
+		}
+		g := factorial(l) * pi
+		if !excludeAdditive {
+			g += math.Pow(minGuessesBeforeGrowingSeq, float64(l-1))
+		}
+		for competingL, competingG := range optimalG[k] {
+			if competingL <= l && competingG <= g {
+				return
+			}
+		}
+		optimalG[k][l] = g
+		optimalM[k][l] = m
+		optimalPi[k][l] = pi
+	}
+
+	bruteforce := func(i, j int) *strengthMatch {
+		return &strengthMatch{pattern: "bruteforce", i: i, j: j, token: string(password[i : j+1])}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.i > 0 {
+				for l := range optimalM[m.i-1] {
+					update(m, l+1)
+				}
+			} else {
+				update(m, 1)
+			}
+		}
+
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforce(i, k)
+			for l, last := range optimalM[i-1] {
+				// Two adjacent bruteforce matches are never better than one.
+				if last.pattern == "bruteforce" {
+					continue
+				}
+				update(m, l+1)
+			}
+		}
+	}
+
+	bestL, bestG := 0, math.Inf(1)
+	for l, g := range optimalG[n-1] {
+		if g < bestG || (g == bestG && l < bestL) {
+			bestL, bestG = l, g
+		}
+	}
+
+	sequence := make([]*strengthMatch, bestL)
+	for k, l := n-1, bestL; k >= 0 && l > 0; l-- {
+		m := optimalM[k][l]
+		sequence[l-1] = m
+		k = m.i - 1
+	}
+
+	return guessResult{guesses: bestG, sequence: sequence}
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+func nCk(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	if k == 0 {
+		return 1
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r *= float64(n)
+		r /= float64(d)
+		n--
+	}
+	return r
+}
+
+func estimateGuesses(m *strengthMatch, passwordLength int) float64 {
+	if m.guesses != 0 {
+		return m.guesses
+	}
+
+	tokenLength := len([]rune(m.token))
+	minGuesses := 1.0
+	if tokenLength < passwordLength {
+		minGuesses = minSubmatchGuessesMultiChar
+		if tokenLength == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		}
+	}
+
+	var guesses float64
+	switch m.pattern {
+	case "bruteforce":
+		guesses = bruteforceGuesses(tokenLength)
+	case "dictionary":
+		guesses = dictionaryGuesses(m)
+	case "spatial":
+		guesses = spatialGuesses(m)
+	case "repeat":
+		guesses = m.baseGuesses * float64(m.repeatCount)
+	case "sequence":
+		guesses = sequenceGuesses(m)
+	case "regex", "date":
+		guesses = dateGuesses(m)
+	}
+
+	m.guesses = math.Max(guesses, minGuesses)
+	return m.guesses
+}
+
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	if math.IsInf(guesses, 1) {
+		guesses = math.MaxFloat64
+	}
+	minGuesses := float64(minSubmatchGuessesMultiChar + 1)
+	if length == 1 {
+		minGuesses = minSubmatchGuessesSingleChar + 1
+	}
+	return math.Max(guesses, minGuesses)
+}
+
+func dictionaryGuesses(m *strengthMatch) float64 {
+	guesses := float64(m.rank) * uppercaseVariations(m.token) * l33tVariations(m)
+	if m.reversed {
+		guesses *= 2
+	}
+	return guesses
+}
+
+// uppercaseVariations counts the capitalisations an attacker would try
+// before reaching token's: a capital first or last letter or all caps are
+// common enough to only double the guesses.
+func uppercaseVariations(token string) float64 {
+	runes := []rune(token)
+	upper, lower := 0, 0
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 {
+		return 2
+	}
+
+	first := unicode.IsUpper(runes[0])
+	last := unicode.IsUpper(runes[len(runes)-1])
+	if upper == 1 && (first || last) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += nCk(upper+lower, i)
+	}
+	return variations
+}
+
+func l33tVariations(m *strengthMatch) float64 {
+	if !m.l33t {
+		return 1
+	}
+
+	variations := 1.0
+	token := []rune(strings.ToLower(m.token))
+	for subbed, unsubbed := range m.sub {
+		s, u := 0, 0
+		for _, r := range token {
+			if r == subbed {
+				s++
+			}
+			if r == unsubbed {
+				u++
+			}
+		}
+		if s == 0 || u == 0 {
+			// Fully substituted: the attacker tries it subbed or not.
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= min(u, s); i++ {
+			possibilities += nCk(u+s, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+func spatialGuesses(m *strengthMatch) float64 {
+	graphs := keyboardGraphs()
+	starts, degree := graphStats(graphs[m.graph])
+
+	length := len([]rune(m.token))
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		possibleTurns := min(m.turns, i-1)
+		for j := 1; j <= possibleTurns; j++ {
+			guesses += nCk(i-1, j-1) * starts * math.Pow(degree, float64(j))
+		}
+	}
+
+	if m.shiftedCount > 0 {
+		shifted := m.shiftedCount
+		unshifted := length - shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= min(shifted, unshifted); i++ {
+				variations += nCk(shifted+unshifted, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+func sequenceGuesses(m *strengthMatch) float64 {
+	runes := []rune(m.token)
+	first := runes[0]
+
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		// Sequences starting at the obvious places are tried first.
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.ascending {
+		base *= 2
+	}
+	return base * float64(len(runes))
+}
+
+func dateGuesses(m *strengthMatch) float64 {
+	yearSpace := math.Max(math.Abs(float64(m.year-referenceYear())), minYearSpace)
+	if m.pattern == "regex" {
+		return yearSpace
+	}
+
+	guesses := yearSpace * 365
+	if m.separator != "" {
+		guesses *= 4
+	}
+	return guesses
+}
@@ -0,0 +1,689 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// The ranked word lists and keyboard adjacency graphs come from zxcvbn
+// (MIT licensed). Each list is ordered by frequency, most common first.
+//
+//go:embed strengthdata
+var strengthDataFS embed.FS
+
+var strengthDictionaryFiles = map[string]string{
+	"passwords":    "strengthdata/passwords.txt",
+	"english":      "strengthdata/english.txt",
+	"female_names": "strengthdata/female_names.txt",
+	"male_names":   "strengthdata/male_names.txt",
+	"surnames":     "strengthdata/surnames.txt",
+}
+
+// strengthMatch is one pattern found in a password, covering the runes
+// i through j inclusive.
+type strengthMatch struct {
+	pattern string // dictionary, spatial, repeat, sequence, regex, date or bruteforce
+	i, j    int
+	token   string
+
+	// dictionary
+	matchedWord string
+	rank        int
+	dictName    string
+	reversed    bool
+	l33t        bool
+	sub         map[rune]rune // l33t character -> letter it stands for
+
+	// spatial
+	graph        string
+	turns        int
+	shiftedCount int
+
+	// repeat
+	baseToken   string
+	baseGuesses float64
+	repeatCount int
+
+	// sequence
+	ascending bool
+
+	// regex and date
+	year      int
+	separator string
+
+	guesses float64 // Cached estimate, 0 until computed
+}
+
+var rankedDictionaries = sync.OnceValue(func() map[string]map[string]int {
+	dictionaries := make(map[string]map[string]int)
+	for name, path := range strengthDictionaryFiles {
+		file, err := strengthDataFS.Open(path)
+		if err != nil {
+			continue
+		}
+
+		ranked := make(map[string]int)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			word := strings.TrimSpace(scanner.Text())
+			if _, ok := ranked[word]; word != "" && !ok {
+				ranked[word] = len(ranked) + 1
+			}
+		}
+		file.Close()
+		dictionaries[name] = ranked
+	}
+	return dictionaries
+})
+
+type adjacencyGraph map[string][]*string
+
+var keyboardGraphs = sync.OnceValue(func() map[string]adjacencyGraph {
+	graphs := make(map[string]adjacencyGraph)
+	data, err := strengthDataFS.ReadFile("strengthdata/keyboards.json")
+	if err != nil {
+		return graphs
+	}
+	json.Unmarshal(data, &graphs)
+	return graphs
+})
+
+// graphStats returns the number of keys and the average number of
+// neighbours per key, used to estimate spatial pattern guesses.
+func graphStats(graph adjacencyGraph) (float64, float64) {
+	total := 0
+	for _, neighbors := range graph {
+		for _, n := range neighbors {
+			if n != nil {
+				total++
+			}
+		}
+	}
+	if len(graph) == 0 {
+		return 0, 0
+	}
+	return float64(len(graph)), float64(total) / float64(len(graph))
+}
+
+// omnimatch runs every matcher over password and returns the matches
+// sorted by position.
+func omnimatch(password []rune, userInputs map[string]int) []*strengthMatch {
+	dictionaries := rankedDictionaries()
+	if len(userInputs) > 0 {
+		withUser := make(map[string]map[string]int, len(dictionaries)+1)
+		for name, dict := range dictionaries {
+			withUser[name] = dict
+		}
+		withUser["user_inputs"] = userInputs
+		dictionaries = withUser
+	}
+
+	var matches []*strengthMatch
+	matches = append(matches, dictionaryMatches(password, dictionaries)...)
+	matches = append(matches, reverseDictionaryMatches(password, dictionaries)...)
+	matches = append(matches, l33tMatches(password, dictionaries)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, repeatMatches(password, userInputs)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, regexMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].i != matches[b].i {
+			return matches[a].i < matches[b].i
+		}
+		return matches[a].j < matches[b].j
+	})
+	return matches
+}
+
+func lowerRunes(password []rune) []rune {
+	lower := make([]rune, len(password))
+	for i, r := range password {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func dictionaryMatches(password []rune, dictionaries map[string]map[string]int) []*strengthMatch {
+	var matches []*strengthMatch
+	lower := lowerRunes(password)
+
+	for i := 0; i < len(password); i++ {
+		for j := i; j < len(password); j++ {
+			word := string(lower[i : j+1])
+			for name, dict := range dictionaries {
+				if rank, ok := dict[word]; ok {
+					matches = append(matches, &strengthMatch{
+						pattern:     "dictionary",
+						i:           i,
+						j:           j,
+						token:       string(password[i : j+1]),
+						matchedWord: word,
+						rank:        rank,
+						dictName:    name,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func reverseRunes(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	return reversed
+}
+
+func reverseDictionaryMatches(password []rune, dictionaries map[string]map[string]int) []*strengthMatch {
+	n := len(password)
+	matches := dictionaryMatches(reverseRunes(password), dictionaries)
+	for _, m := range matches {
+		m.token = string(reverseRunes([]rune(m.token)))
+		m.reversed = true
+		m.i, m.j = n-1-m.j, n-1-m.i
+	}
+	return matches
+}
+
+var l33tTable = map[rune][]rune{
+	'a': []rune("4@"),
+	'b': []rune("8"),
+	'c': []rune("({[<"),
+	'e': []rune("3"),
+	'g': []rune("69"),
+	'i': []rune("1!|"),
+	'l': []rune("1|7"),
+	'o': []rune("0"),
+	's': []rune("$5"),
+	't': []rune("+7"),
+	'x': []rune("%"),
+	'z': []rune("2"),
+}
+
+// l33tSubs enumerates the ways of reading the l33t characters present in
+// password as letters, e.g. "1" as either "i" or "l". As in zxcvbn, each
+// letter is written with at most one l33t character per reading, so "p4@ss"
+// reads as "pa@ss" or "p4ass" but not "paass".
+func l33tSubs(password []rune) []map[rune]rune {
+	options := make(map[rune][]rune)
+	for letter, subs := range l33tTable {
+		for _, sub := range subs {
+			for _, r := range password {
+				if r == sub {
+					options[letter] = append(options[letter], sub)
+					break
+				}
+			}
+		}
+	}
+
+	var letters []rune
+	for letter := range options {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(a, b int) bool { return letters[a] < letters[b] })
+
+	result := []map[rune]rune{{}}
+	for _, letter := range letters {
+		var next []map[rune]rune
+		seen := make(map[string]bool)
+		add := func(m map[rune]rune) {
+			var subs []rune
+			for sub := range m {
+				subs = append(subs, sub)
+			}
+			sort.Slice(subs, func(a, b int) bool { return subs[a] < subs[b] })
+			var key []rune
+			for _, sub := range subs {
+				key = append(key, sub, m[sub])
+			}
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				next = append(next, m)
+			}
+		}
+		for _, sub := range options[letter] {
+			for _, partial := range result {
+				// A character another letter already claimed is read as
+				// either letter.
+				if _, taken := partial[sub]; taken {
+					add(partial)
+				}
+				m := make(map[rune]rune, len(partial)+1)
+				for k, v := range partial {
+					m[k] = v
+				}
+				m[sub] = letter
+				add(m)
+			}
+		}
+		result = next
+	}
+
+	if len(result) == 1 && len(result[0]) == 0 {
+		return nil
+	}
+	return result
+}
+
+func l33tMatches(password []rune, dictionaries map[string]map[string]int) []*strengthMatch {
+	var matches []*strengthMatch
+	seen := make(map[string]bool)
+
+	for _, sub := range l33tSubs(password) {
+		translated := make([]rune, len(password))
+		for i, r := range password {
+			if letter, ok := sub[r]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+
+		for _, m := range dictionaryMatches(translated, dictionaries) {
+			token := password[m.i : m.j+1]
+			// Single characters are better covered by bruteforce.
+			if len(token) <= 1 || strings.ToLower(string(token)) == m.matchedWord {
+				continue
+			}
+
+			matchSub := make(map[rune]rune)
+			for _, r := range token {
+				if letter, ok := sub[r]; ok {
+					matchSub[r] = letter
+				}
+			}
+			if len(matchSub) == 0 {
+				continue
+			}
+
+			key := m.dictName + ":" + strconv.Itoa(m.i) + ":" + strconv.Itoa(m.j) + ":" + m.matchedWord
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			m.token = string(token)
+			m.l33t = true
+			m.sub = matchSub
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func isShifted(r rune) bool {
+	return strings.ContainsRune(`~!@#$%^&*()_+QWERTYUIOPASDFGHJKL:"ZXCVBNM<>?{}|`, r)
+}
+
+func spatialMatches(password []rune) []*strengthMatch {
+	var matches []*strengthMatch
+	graphs := keyboardGraphs()
+
+	names := make([]string, 0, len(graphs))
+	for name := range graphs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		graph := graphs[name]
+		keyboard := name == "qwerty" || name == "dvorak"
+
+		for i := 0; i < len(password)-1; {
+			j := i + 1
+			lastDirection := -1
+			turns := 0
+			shiftedCount := 0
+			if keyboard && isShifted(password[i]) {
+				shiftedCount = 1
+			}
+
+			for {
+				found := false
+				if j < len(password) {
+					current := string(password[j])
+					for direction, adjacent := range graph[string(password[j-1])] {
+						if adjacent == nil || !strings.Contains(*adjacent, current) {
+							continue
+						}
+						found = true
+						// Index 1 of a neighbour pair is the shifted key.
+						if strings.Index(*adjacent, current) == 1 {
+							shiftedCount++
+						}
+						if lastDirection != direction {
+							turns++
+							lastDirection = direction
+						}
+						break
+					}
+				}
+
+				if found {
+					j++
+					continue
+				}
+
+				if j-i > 2 {
+					matches = append(matches, &strengthMatch{
+						pattern:      "spatial",
+						i:            i,
+						j:            j - 1,
+						token:        string(password[i:j]),
+						graph:        name,
+						turns:        turns,
+						shiftedCount: shiftedCount,
+					})
+				}
+				i = j
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func runesEqual(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// repeatCount returns how many times base repeats back to back at the start
+// of runes.
+func repeatCount(runes, base []rune) int {
+	count := 0
+	for len(runes) >= len(base) && runesEqual(runes[:len(base)], base) {
+		count++
+		runes = runes[len(base):]
+	}
+	return count
+}
+
+// repeatMatches finds runs such as "aaa" or "abcabc": starting from the
+// leftmost position it takes the longest repeated region and describes it
+// by the shortest unit that tiles it.
+func repeatMatches(password []rune, userInputs map[string]int) []*strengthMatch {
+	var matches []*strengthMatch
+
+	for i := 0; i < len(password); {
+		bestLength, bestBase := 0, 0
+		for baseLen := 1; i+2*baseLen <= len(password); baseLen++ {
+			count := repeatCount(password[i:], password[i:i+baseLen])
+			if count >= 2 && count*baseLen > bestLength {
+				bestLength, bestBase = count*baseLen, baseLen
+			}
+		}
+		if bestLength == 0 {
+			i++
+			continue
+		}
+
+		region := password[i : i+bestLength]
+		for baseLen := 1; baseLen <= bestBase; baseLen++ {
+			if bestLength%baseLen == 0 && repeatCount(region, region[:baseLen]) == bestLength/baseLen {
+				bestBase = baseLen
+				break
+			}
+		}
+
+		base := region[:bestBase]
+		baseResult := mostGuessableSequence(base, omnimatch(base, userInputs), false)
+		matches = append(matches, &strengthMatch{
+			pattern:     "repeat",
+			i:           i,
+			j:           i + bestLength - 1,
+			token:       string(region),
+			baseToken:   string(base),
+			baseGuesses: baseResult.guesses,
+			repeatCount: bestLength / bestBase,
+		})
+		i += bestLength
+	}
+	return matches
+}
+
+const maxSequenceDelta = 5
+
+func sequenceMatches(password []rune) []*strengthMatch {
+	if len(password) <= 1 {
+		return nil
+	}
+
+	var matches []*strengthMatch
+	update := func(i, j, delta int) {
+		abs := delta
+		if abs < 0 {
+			abs = -abs
+		}
+		if (j-i > 1 || abs == 1) && abs > 0 && abs <= maxSequenceDelta {
+			matches = append(matches, &strengthMatch{
+				pattern:   "sequence",
+				i:         i,
+				j:         j,
+				token:     string(password[i : j+1]),
+				ascending: delta > 0,
+			})
+		}
+	}
+
+	i := 0
+	lastDelta := 0
+	haveDelta := false
+	for k := 1; k < len(password); k++ {
+		delta := int(password[k]) - int(password[k-1])
+		if !haveDelta {
+			lastDelta = delta
+			haveDelta = true
+		}
+		if delta == lastDelta {
+			continue
+		}
+		j := k - 1
+		update(i, j, lastDelta)
+		i = j
+		lastDelta = delta
+	}
+	update(i, len(password)-1, lastDelta)
+	return matches
+}
+
+var recentYearRegexp = regexp.MustCompile(`19\d\d|20\d\d`)
+
+func regexMatches(password []rune) []*strengthMatch {
+	var matches []*strengthMatch
+	text := string(password)
+	for _, loc := range recentYearRegexp.FindAllStringIndex(text, -1) {
+		// Convert byte offsets to rune offsets.
+		i := len([]rune(text[:loc[0]]))
+		token := text[loc[0]:loc[1]]
+		year, _ := strconv.Atoi(token)
+		matches = append(matches, &strengthMatch{
+			pattern: "regex",
+			i:       i,
+			j:       i + len(token) - 1,
+			token:   token,
+			year:    year,
+		})
+	}
+	return matches
+}
+
+const (
+	dateMinYear = 1000
+	dateMaxYear = 2050
+)
+
+var dateSplits = map[int][][2]int{
+	4: {{1, 2}, {2, 3}},
+	5: {{1, 3}, {2, 3}},
+	6: {{1, 2}, {2, 4}, {4, 5}},
+	7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+	8: {{2, 4}, {4, 6}},
+}
+
+var dateWithSeparatorRegexp = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+
+func referenceYear() int {
+	return time.Now().Year()
+}
+
+func twoToFourDigitYear(year int) int {
+	switch {
+	case year > 99:
+		return year
+	case year > 50:
+		return year + 1900
+	default:
+		return year + 2000
+	}
+}
+
+func mapIntsToDM(a, b int) (int, int, bool) {
+	for _, dm := range [][2]int{{a, b}, {b, a}} {
+		if dm[0] >= 1 && dm[0] <= 31 && dm[1] >= 1 && dm[1] <= 12 {
+			return dm[0], dm[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// mapIntsToDMY interprets three numbers as a day, month and year in any of
+// the common orders, returning the year if they form a plausible date.
+func mapIntsToDMY(ints [3]int) (int, bool) {
+	if ints[1] > 31 || ints[1] <= 0 {
+		return 0, false
+	}
+
+	over12, over31, under1 := 0, 0, 0
+	for _, n := range ints {
+		if (n > 99 && n < dateMinYear) || n > dateMaxYear {
+			return 0, false
+		}
+		if n > 31 {
+			over31++
+		}
+		if n > 12 {
+			over12++
+		}
+		if n <= 0 {
+			under1++
+		}
+	}
+	if over31 >= 2 || over12 == 3 || under1 >= 2 {
+		return 0, false
+	}
+
+	splits := [][3]int{{ints[2], ints[0], ints[1]}, {ints[0], ints[1], ints[2]}}
+	for _, split := range splits {
+		if split[0] >= dateMinYear && split[0] <= dateMaxYear {
+			if _, _, ok := mapIntsToDM(split[1], split[2]); ok {
+				return split[0], true
+			}
+			return 0, false
+		}
+	}
+	for _, split := range splits {
+		if _, _, ok := mapIntsToDM(split[1], split[2]); ok {
+			return twoToFourDigitYear(split[0]), true
+		}
+	}
+	return 0, false
+}
+
+func isDigits(runes []rune) bool {
+	for _, r := range runes {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return len(runes) > 0
+}
+
+func dateMatches(password []rune) []*strengthMatch {
+	var matches []*strengthMatch
+	ref := referenceYear()
+	distance := func(year int) int {
+		if year > ref {
+			return year - ref
+		}
+		return ref - year
+	}
+
+	// Dates without separators, e.g. 13041987 or 1387.
+	for i := 0; i+4 <= len(password); i++ {
+		for j := i + 3; j <= i+7 && j < len(password); j++ {
+			token := password[i : j+1]
+			if !isDigits(token) {
+				continue
+			}
+
+			bestYear, found := 0, false
+			for _, split := range dateSplits[len(token)] {
+				a, _ := strconv.Atoi(string(token[:split[0]]))
+				b, _ := strconv.Atoi(string(token[split[0]:split[1]]))
+				c, _ := strconv.Atoi(string(token[split[1]:]))
+				if year, ok := mapIntsToDMY([3]int{a, b, c}); ok {
+					if !found || distance(year) < distance(bestYear) {
+						bestYear, found = year, true
+					}
+				}
+			}
+			if found {
+				matches = append(matches, &strengthMatch{pattern: "date", i: i, j: j, token: string(token), year: bestYear})
+			}
+		}
+	}
+
+	// Dates with separators, e.g. 13/04/1987 or 1987-4-13.
+	for i := 0; i+6 <= len(password); i++ {
+		for j := i + 5; j <= i+9 && j < len(password); j++ {
+			token := string(password[i : j+1])
+			groups := dateWithSeparatorRegexp.FindStringSubmatch(token)
+			if groups == nil || groups[2] != groups[4] {
+				continue
+			}
+			a, _ := strconv.Atoi(groups[1])
+			b, _ := strconv.Atoi(groups[3])
+			c, _ := strconv.Atoi(groups[5])
+			if year, ok := mapIntsToDMY([3]int{a, b, c}); ok {
+				matches = append(matches, &strengthMatch{pattern: "date", i: i, j: j, token: token, year: year, separator: groups[2]})
+			}
+		}
+	}
+
+	// Drop dates that sit inside a longer date match.
+	var filtered []*strengthMatch
+	for _, m := range matches {
+		contained := false
+		for _, other := range matches {
+			if m != other && other.i <= m.i && other.j >= m.j {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
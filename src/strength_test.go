@@ -0,0 +1,551 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// The fixtures below are ported from zxcvbn's test-matching.coffee and
+// test-scoring.coffee.
+
+func matchSpans(matches []*strengthMatch) []string {
+	var spans []string
+	for _, m := range matches {
+		spans = append(spans, m.token)
+	}
+	sort.Strings(spans)
+	return spans
+}
+
+func TestDictionaryMatches(t *testing.T) {
+	dicts := map[string]map[string]int{
+		"d1": {"motherboard": 1, "mother": 2, "board": 3, "abcd": 4, "cdef": 5},
+		"d2": {"z": 1, "8": 2, "99": 3, "$": 4, "asdf1234&*": 5},
+	}
+
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"motherboard", []string{"board", "mother", "motherboard"}},
+		{"abcdef", []string{"abcd", "cdef"}},
+		{"BoaRdZ", []string{"BoaRd", "Z"}},
+		{"q%%123456z", []string{"z"}},
+		{"asdf1234&*", []string{"asdf1234&*"}},
+	}
+	for _, tt := range tests {
+		if got := matchSpans(dictionaryMatches([]rune(tt.password), dicts)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.password, got, tt.want)
+		}
+	}
+
+	matches := dictionaryMatches([]rune("BoaRd"), dicts)
+	if len(matches) != 1 || matches[0].matchedWord != "board" || matches[0].rank != 3 || matches[0].dictName != "d1" {
+		t.Errorf("BoaRd: got %+v", matches)
+	}
+}
+
+func TestReverseDictionaryMatches(t *testing.T) {
+	dicts := map[string]map[string]int{"d1": {"123": 1, "321": 2, "456": 3, "654": 4}}
+	matches := reverseDictionaryMatches([]rune("0123456789"), dicts)
+	sort.Slice(matches, func(a, b int) bool { return matches[a].i < matches[b].i })
+
+	want := []struct {
+		token, word string
+		rank, i, j  int
+	}{
+		{"123", "321", 2, 1, 3},
+		{"456", "654", 4, 4, 6},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("got %d matches, want %d", len(matches), len(want))
+	}
+	for k, w := range want {
+		m := matches[k]
+		if m.token != w.token || m.matchedWord != w.word || m.rank != w.rank || m.i != w.i || m.j != w.j || !m.reversed {
+			t.Errorf("match %d: got %+v, want %+v", k, m, w)
+		}
+	}
+}
+
+func TestL33tMatches(t *testing.T) {
+	dicts := map[string]map[string]int{
+		"words":  {"aac": 1, "password": 3, "paassword": 4, "asdf0": 5},
+		"words2": {"cgo": 1},
+	}
+
+	tests := []struct {
+		password string
+		want     map[string]map[rune]rune // token -> substitutions
+	}{
+		{"", nil},
+		{"password", nil},
+		{"p4ssword", map[string]map[rune]rune{"p4ssword": {'4': 'a'}}},
+		{"p@ssw0rd", map[string]map[rune]rune{"p@ssw0rd": {'@': 'a', '0': 'o'}}},
+		{"aSdfO{G0asDfO", map[string]map[rune]rune{"{G0": {'{': 'c', '0': 'o'}}},
+		{"@a(go{G0", map[string]map[rune]rune{"@a(": {'@': 'a', '(': 'c'}, "(go": {'(': 'c'}, "{G0": {'{': 'c', '0': 'o'}}},
+		// Single characters are left to bruteforce.
+		{"4 1 @", nil},
+		// One letter can't be written two ways in the same reading.
+		{"p4@ssword", nil},
+	}
+	for _, tt := range tests {
+		got := make(map[string]map[rune]rune)
+		for _, m := range l33tMatches([]rune(tt.password), dicts) {
+			if !m.l33t {
+				t.Errorf("%q: match %q not marked l33t", tt.password, m.token)
+			}
+			got[m.token] = m.sub
+		}
+		if len(tt.want) == 0 && len(got) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestSpatialMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		graph   string
+		turns   int
+		shifted int
+	}{
+		{"12345", "qwerty", 1, 0},
+		{"@WSX", "qwerty", 1, 4},
+		{"6tfGHJ", "qwerty", 2, 3},
+		{"hGFd", "qwerty", 1, 2},
+		{"/;p09876yhn", "qwerty", 3, 0},
+		{"Xdr%", "qwerty", 1, 2},
+		{"159-", "keypad", 1, 0},
+		{"*84", "keypad", 1, 0},
+		{"/8520", "keypad", 1, 0},
+		{"369", "keypad", 1, 0},
+		{"/963.", "mac_keypad", 1, 0},
+		{"*-632.0214", "mac_keypad", 9, 0},
+		{"aoEP%yIxkjq:", "dvorak", 4, 5},
+		{";qoaOQ:Aoq;a", "dvorak", 11, 4},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, m := range spatialMatches([]rune(tt.pattern)) {
+			if m.graph != tt.graph {
+				continue
+			}
+			found = true
+			if m.token != tt.pattern || m.turns != tt.turns || m.shiftedCount != tt.shifted {
+				t.Errorf("%q on %s: got %q turns=%d shifted=%d, want turns=%d shifted=%d",
+					tt.pattern, tt.graph, m.token, m.turns, m.shiftedCount, tt.turns, tt.shifted)
+			}
+		}
+		if !found {
+			t.Errorf("%q: no %s match", tt.pattern, tt.graph)
+		}
+	}
+
+	matches := spatialMatches([]rune("rz!6tfGHJ%z"))
+	found := false
+	for _, m := range matches {
+		if m.graph == "qwerty" && m.token == "6tfGHJ" && m.i == 3 && m.j == 8 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("embedded pattern: no qwerty match for 6tfGHJ at [3,8], got %+v", matches)
+	}
+
+	for _, password := range []string{"", "/", "qw", "*/"} {
+		if matches := spatialMatches([]rune(password)); len(matches) != 0 {
+			t.Errorf("%q: got %d spatial matches, want none", password, len(matches))
+		}
+	}
+}
+
+func TestSequenceMatches(t *testing.T) {
+	for _, password := range []string{"", "a", "1"} {
+		if matches := sequenceMatches([]rune(password)); len(matches) != 0 {
+			t.Errorf("%q: got %d sequence matches, want none", password, len(matches))
+		}
+	}
+
+	matches := sequenceMatches([]rune("abcbabc"))
+	want := []struct {
+		token     string
+		i, j      int
+		ascending bool
+	}{
+		{"abc", 0, 2, true},
+		{"cba", 2, 4, false},
+		{"abc", 4, 6, true},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("abcbabc: got %d matches, want %d", len(matches), len(want))
+	}
+	for k, w := range want {
+		m := matches[k]
+		if m.token != w.token || m.i != w.i || m.j != w.j || m.ascending != w.ascending {
+			t.Errorf("abcbabc match %d: got %q [%d,%d] ascending=%v, want %+v", k, m.token, m.i, m.j, m.ascending, w)
+		}
+	}
+
+	tests := []struct {
+		pattern   string
+		ascending bool
+	}{
+		{"ABC", true}, {"CBA", false}, {"PQR", true}, {"RQP", false}, {"XYZ", true}, {"ZYX", false},
+		{"abcd", true}, {"dcba", false}, {"jihg", false}, {"wxyz", true}, {"zxvt", false},
+		{"0369", true}, {"97531", false},
+	}
+	for _, tt := range tests {
+		password := "!" + tt.pattern + "!"
+		found := false
+		for _, m := range sequenceMatches([]rune(password)) {
+			if m.token == tt.pattern {
+				found = true
+				if m.i != 1 || m.ascending != tt.ascending {
+					t.Errorf("%q: got i=%d ascending=%v", tt.pattern, m.i, m.ascending)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%q: no sequence match in %q", tt.pattern, password)
+		}
+	}
+}
+
+func TestRepeatMatches(t *testing.T) {
+	for _, password := range []string{"", "#"} {
+		if matches := repeatMatches([]rune(password), nil); len(matches) != 0 {
+			t.Errorf("%q: got %d repeat matches, want none", password, len(matches))
+		}
+	}
+
+	tests := []struct {
+		password string
+		tokens   []string
+		bases    []string
+	}{
+		{"&&&&&", []string{"&&&&&"}, []string{"&"}},
+		{"y4@&&&&&u%7", []string{"&&&&&"}, []string{"&"}},
+		{"BBB1111aaaaa@@@@@@", []string{"BBB", "1111", "aaaaa", "@@@@@@"}, []string{"B", "1", "a", "@"}},
+		{"2818BBBbzsdf1111@*&@!aaaaaEUDA@@@@@@1729", []string{"BBB", "1111", "aaaaa", "@@@@@@"}, []string{"B", "1", "a", "@"}},
+		{"abab", []string{"abab"}, []string{"ab"}},
+		{"aabaab", []string{"aabaab"}, []string{"aab"}},
+		{"abababab", []string{"abababab"}, []string{"ab"}},
+		{"batterystaplebatterystaplebatterystaple", []string{"batterystaplebatterystaplebatterystaple"}, []string{"batterystaple"}},
+	}
+	for _, tt := range tests {
+		var tokens, bases []string
+		for _, m := range repeatMatches([]rune(tt.password), nil) {
+			tokens = append(tokens, m.token)
+			bases = append(bases, m.baseToken)
+		}
+		if !reflect.DeepEqual(tokens, tt.tokens) || !reflect.DeepEqual(bases, tt.bases) {
+			t.Errorf("%q: got %q with bases %q, want %q with bases %q", tt.password, tokens, bases, tt.tokens, tt.bases)
+		}
+	}
+}
+
+func TestRegexMatches(t *testing.T) {
+	for _, year := range []string{"1922", "2017"} {
+		matches := regexMatches([]rune(year))
+		if len(matches) != 1 || matches[0].token != year || matches[0].i != 0 || matches[0].j != 3 {
+			t.Errorf("%q: got %+v", year, matches)
+		}
+	}
+}
+
+func TestDateMatches(t *testing.T) {
+	for _, sep := range []string{"", " ", "-", "/", `\`, "_", "."} {
+		password := "13" + sep + "2" + sep + "1921"
+		matches := dateMatches([]rune(password))
+		if len(matches) != 1 || matches[0].token != password || matches[0].year != 1921 || matches[0].separator != sep {
+			t.Errorf("%q: got %+v", password, matches)
+		}
+	}
+
+	for _, password := range []string{"8888", "88/8/8"} {
+		matches := dateMatches([]rune(password))
+		if len(matches) != 1 || matches[0].year != 1988 {
+			t.Errorf("%q: got %+v, want year 1988", password, matches)
+		}
+	}
+
+	tests := []struct {
+		password string
+		token    string
+		i, j     int
+		year     int
+	}{
+		{"111504", "111504", 0, 5, 2004},
+		{"1191999", "1191999", 0, 6, 1999},
+		{"11082000", "11082000", 0, 7, 2000},
+		{"9122005", "9122005", 0, 6, 2005},
+		{"02/02/02", "02/02/02", 0, 7, 2002},
+		{"a1/1/91!", "1/1/91", 1, 6, 1991},
+		{"912/20/919", "12/20/91", 1, 8, 1991},
+	}
+	for _, tt := range tests {
+		matches := dateMatches([]rune(tt.password))
+		found := false
+		for _, m := range matches {
+			if m.token == tt.token && m.i == tt.i && m.j == tt.j && m.year == tt.year {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%q: no date %q [%d,%d] in %d, got %+v", tt.password, tt.token, tt.i, tt.j, tt.year, matches)
+		}
+	}
+
+	matches := dateMatches([]rune("12/20/1991.12.20"))
+	if got := matchSpans(matches); !reflect.DeepEqual(got, []string{"12/20/1991", "1991.12.20"}) {
+		t.Errorf("overlapping dates: got %q", got)
+	}
+}
+
+func TestGuessesToScore(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		score   int
+	}{
+		{1, 0}, {1e3, 0}, {1e3 + 5, 1}, {1e6, 1}, {1e6 + 5, 2}, {1e8, 2}, {1e8 + 5, 3}, {1e10, 3}, {1e10 + 5, 4}, {1e20, 4},
+	}
+	for _, tt := range tests {
+		if got := guessesToScore(tt.guesses); got != tt.score {
+			t.Errorf("guessesToScore(%g) = %d, want %d", tt.guesses, got, tt.score)
+		}
+	}
+}
+
+func TestBruteforceGuesses(t *testing.T) {
+	tests := []struct {
+		length  int
+		guesses float64
+	}{
+		{1, minSubmatchGuessesSingleChar + 1},
+		{2, 100},
+		{3, 1000},
+		{10, 1e10},
+	}
+	for _, tt := range tests {
+		if got := bruteforceGuesses(tt.length); got != tt.guesses {
+			t.Errorf("bruteforceGuesses(%d) = %g, want %g", tt.length, got, tt.guesses)
+		}
+	}
+}
+
+func TestEstimateGuesses(t *testing.T) {
+	cached := &strengthMatch{pattern: "dictionary", token: "a", guesses: 1}
+	if got := estimateGuesses(cached, 1); got != 1 {
+		t.Errorf("cached guesses: got %g, want 1", got)
+	}
+
+	m := &strengthMatch{pattern: "date", token: "1977", year: 1977, separator: "/"}
+	if got, want := estimateGuesses(m, 4), dateGuesses(&strengthMatch{pattern: "date", token: "1977", year: 1977, separator: "/"}); got != want {
+		t.Errorf("date: got %g, want %g", got, want)
+	}
+}
+
+func TestRepeatGuesses(t *testing.T) {
+	tests := []struct {
+		token, base string
+		count       int
+	}{
+		{"aa", "a", 2},
+		{"999", "9", 3},
+		{"$$$$", "$", 4},
+		{"abab", "ab", 2},
+		{"batterystaplebatterystaplebatterystaple", "batterystaple", 3},
+	}
+	for _, tt := range tests {
+		base := []rune(tt.base)
+		baseGuesses := mostGuessableSequence(base, omnimatch(base, nil), false).guesses
+		m := &strengthMatch{pattern: "repeat", token: tt.token, baseToken: tt.base, baseGuesses: baseGuesses, repeatCount: tt.count}
+		if got, want := estimateGuesses(m, len(tt.token)), baseGuesses*float64(tt.count); got != want {
+			t.Errorf("%q: got %g, want %g", tt.token, got, want)
+		}
+	}
+}
+
+func TestSequenceGuesses(t *testing.T) {
+	tests := []struct {
+		token     string
+		ascending bool
+		guesses   float64
+	}{
+		{"ab", true, 4 * 2},         // Obvious start * length
+		{"XYZ", true, 26 * 3},       // Letters * length
+		{"4567", true, 10 * 4},      // Digits * length
+		{"7654", false, 10 * 4 * 2}, // Digits * length * descending
+		{"ZYX", false, 4 * 3 * 2},   // Obvious start * length * descending
+	}
+	for _, tt := range tests {
+		m := &strengthMatch{pattern: "sequence", token: tt.token, ascending: tt.ascending}
+		if got := sequenceGuesses(m); got != tt.guesses {
+			t.Errorf("%q: got %g, want %g", tt.token, got, tt.guesses)
+		}
+	}
+}
+
+func TestDateGuesses(t *testing.T) {
+	year := referenceYear()
+
+	regex := &strengthMatch{pattern: "regex", token: "1972", year: 1972}
+	if got, want := dateGuesses(regex), math.Abs(float64(year-1972)); got != want {
+		t.Errorf("recent year 1972: got %g, want %g", got, want)
+	}
+	regex = &strengthMatch{pattern: "regex", token: "2005", year: year - 1}
+	if got := dateGuesses(regex); got != minYearSpace {
+		t.Errorf("recent year close to now: got %g, want %d", got, minYearSpace)
+	}
+
+	date := &strengthMatch{pattern: "date", token: "1123", year: 1923}
+	if got, want := dateGuesses(date), 365*math.Abs(float64(year-1923)); got != want {
+		t.Errorf("1123: got %g, want %g", got, want)
+	}
+	date = &strengthMatch{pattern: "date", token: "1/1/2010", year: year, separator: "/"}
+	if got, want := dateGuesses(date), float64(365*minYearSpace*4); got != want {
+		t.Errorf("1/1/2010: got %g, want %g", got, want)
+	}
+}
+
+func TestSpatialGuesses(t *testing.T) {
+	starts, degree := graphStats(keyboardGraphs()["qwerty"])
+
+	m := &strengthMatch{pattern: "spatial", token: "zxcvbn", graph: "qwerty", turns: 1}
+	base := starts * degree * 5
+	if got := spatialGuesses(m); math.Abs(got-base) > 1e-6 {
+		t.Errorf("zxcvbn: got %g, want %g", got, base)
+	}
+
+	m = &strengthMatch{pattern: "spatial", token: "ZxCvbn", graph: "qwerty", turns: 1, shiftedCount: 2}
+	if got, want := spatialGuesses(m), base*(nCk(6, 2)+nCk(6, 1)); math.Abs(got-want) > 1e-6 {
+		t.Errorf("ZxCvbn: got %g, want %g", got, want)
+	}
+
+	m = &strengthMatch{pattern: "spatial", token: "ZXCVBN", graph: "qwerty", turns: 1, shiftedCount: 6}
+	if got, want := spatialGuesses(m), base*2; math.Abs(got-want) > 1e-6 {
+		t.Errorf("ZXCVBN: got %g, want %g", got, want)
+	}
+
+	m = &strengthMatch{pattern: "spatial", token: "zxcft6yh", graph: "qwerty", turns: 3}
+	want := 0.0
+	for i := 2; i <= 8; i++ {
+		for j := 1; j <= min(3, i-1); j++ {
+			want += nCk(i-1, j-1) * starts * math.Pow(degree, float64(j))
+		}
+	}
+	if got := spatialGuesses(m); math.Abs(got-want)/want > 1e-12 {
+		t.Errorf("zxcft6yh: got %g, want %g", got, want)
+	}
+}
+
+func TestDictionaryGuesses(t *testing.T) {
+	tests := []struct {
+		m       strengthMatch
+		guesses float64
+	}{
+		{strengthMatch{token: "aaaaa", rank: 32}, 32},
+		{strengthMatch{token: "AAAaaa", rank: 32}, 32 * uppercaseVariations("AAAaaa")},
+		{strengthMatch{token: "aaa", rank: 32, reversed: true}, 32 * 2},
+		{strengthMatch{token: "aaa@@@", rank: 32, l33t: true, sub: map[rune]rune{'@': 'a'}}, 32 * (nCk(6, 1) + nCk(6, 2) + nCk(6, 3))},
+		{strengthMatch{token: "AaA@@@", rank: 32, l33t: true, sub: map[rune]rune{'@': 'a'}}, 32 * (nCk(6, 1) + nCk(6, 2) + nCk(6, 3)) * uppercaseVariations("AaA@@@")},
+	}
+	for _, tt := range tests {
+		m := tt.m
+		m.pattern = "dictionary"
+		if got := dictionaryGuesses(&m); got != tt.guesses {
+			t.Errorf("%q: got %g, want %g", m.token, got, tt.guesses)
+		}
+	}
+}
+
+func TestUppercaseVariations(t *testing.T) {
+	tests := []struct {
+		word       string
+		variations float64
+	}{
+		{"", 1},
+		{"a", 1},
+		{"A", 2},
+		{"abcdef", 1},
+		{"Abcdef", 2},
+		{"abcdeF", 2},
+		{"ABCDEF", 2},
+		{"aBcdef", nCk(6, 1)},
+		{"aBcDef", nCk(6, 1) + nCk(6, 2)},
+		{"ABCDEf", nCk(6, 1)},
+		{"aBCDEf", nCk(6, 1) + nCk(6, 2)},
+		{"ABCdef", nCk(6, 1) + nCk(6, 2) + nCk(6, 3)},
+	}
+	for _, tt := range tests {
+		if got := uppercaseVariations(tt.word); got != tt.variations {
+			t.Errorf("uppercaseVariations(%q) = %g, want %g", tt.word, got, tt.variations)
+		}
+	}
+}
+
+func TestL33tVariations(t *testing.T) {
+	if got := l33tVariations(&strengthMatch{token: "p4ssw0rd"}); got != 1 {
+		t.Errorf("non-l33t match: got %g, want 1", got)
+	}
+
+	tests := []struct {
+		word       string
+		variations float64
+		sub        map[rune]rune
+	}{
+		{"", 1, map[rune]rune{}},
+		{"a", 1, map[rune]rune{}},
+		{"4", 2, map[rune]rune{'4': 'a'}},
+		{"4pple", 2, map[rune]rune{'4': 'a'}},
+		{"abcet", 1, map[rune]rune{}},
+		{"4bcet", 2, map[rune]rune{'4': 'a'}},
+		{"a8cet", 2, map[rune]rune{'8': 'b'}},
+		{"abce+", 2, map[rune]rune{'+': 't'}},
+		{"48cet", 4, map[rune]rune{'4': 'a', '8': 'b'}},
+		{"a4a4aa", nCk(6, 2) + nCk(6, 1), map[rune]rune{'4': 'a'}},
+		{"4a4a44", nCk(6, 2) + nCk(6, 1), map[rune]rune{'4': 'a'}},
+		{"a44att+", (nCk(4, 2) + nCk(4, 1)) * nCk(3, 1), map[rune]rune{'4': 'a', '+': 't'}},
+		// Capitalization doesn't affect the l33t variations.
+		{"Aa44aA", nCk(6, 2) + nCk(6, 1), map[rune]rune{'4': 'a'}},
+	}
+	for _, tt := range tests {
+		m := &strengthMatch{token: tt.word, l33t: true, sub: tt.sub}
+		if got := l33tVariations(m); got != tt.variations {
+			t.Errorf("l33tVariations(%q) = %g, want %g", tt.word, got, tt.variations)
+		}
+	}
+}
+
+func TestEstimateStrengthFeedback(t *testing.T) {
+	tests := []struct {
+		password string
+		score    int
+		warning  string
+	}{
+		{"password", 0, "This is a top-10 common password"},
+		{"p4ssw0rd", 0, "This is similar to a commonly used password"},
+		{"drowssap", 0, "This is similar to a commonly used password"},
+		{"aaaaaaaaaaaa", 0, `Repeats like "aaa" are easy to guess`},
+		{"abcdefghijkl", 0, "Sequences like abc or 6543 are easy to guess"},
+		{"13/2/1921", 1, "Dates are often easy to guess"},
+		{"correcthorsebatterystaple", 4, ""},
+	}
+	for _, tt := range tests {
+		result := estimateStrength(tt.password)
+		if result.Score != tt.score || result.Warning != tt.warning {
+			t.Errorf("%q: got score %d, warning %q; want %d, %q", tt.password, result.Score, result.Warning, tt.score, tt.warning)
+		}
+	}
+
+	result := estimateStrength("alice1984", "alice@example.com")
+	if result.Warning != "Passwords based on the entry name or account are easy to guess" {
+		t.Errorf("user input: got warning %q", result.Warning)
+	}
+}
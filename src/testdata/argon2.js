@@ -0,0 +1,221 @@
+// Standalone BLAKE2b (RFC 7693) and Argon2 (RFC 9106) used to build the
+// test fixtures independently of the Go code under test. Slow, but only
+// run by hand: node gen_bitwarden.js / node gen_kdbx.js.
+'use strict';
+
+const M64 = (1n << 64n) - 1n;
+const IV = [
+  0x6a09e667f3bcc908n, 0xbb67ae8584caa73bn, 0x3c6ef372fe94f82bn, 0xa54ff53a5f1d36f1n,
+  0x510e527fade682d1n, 0x9b05688c2b3e6c1fn, 0x1f83d9abfb41bd6bn, 0x5be0cd19137e2179n,
+];
+const SIGMA = [
+  [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15],
+  [14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3],
+  [11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4],
+  [7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8],
+  [9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13],
+  [2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9],
+  [12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11],
+  [13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10],
+  [6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5],
+  [10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0],
+];
+
+const rotr = (x, n) => ((x >> BigInt(n)) | (x << BigInt(64 - n))) & M64;
+
+function blake2bCompress(h, block, t, last) {
+  const m = [];
+  for (let i = 0; i < 16; i++) m.push(block.readBigUInt64LE(i * 8));
+  const v = h.concat(IV);
+  v[12] ^= t & M64;
+  if (last) v[14] ^= M64;
+  const g = (a, b, c, d, x, y) => {
+    v[a] = (v[a] + v[b] + x) & M64; v[d] = rotr(v[d] ^ v[a], 32);
+    v[c] = (v[c] + v[d]) & M64; v[b] = rotr(v[b] ^ v[c], 24);
+    v[a] = (v[a] + v[b] + y) & M64; v[d] = rotr(v[d] ^ v[a], 16);
+    v[c] = (v[c] + v[d]) & M64; v[b] = rotr(v[b] ^ v[c], 63);
+  };
+  for (let r = 0; r < 12; r++) {
+    const s = SIGMA[r % 10];
+    g(0, 4, 8, 12, m[s[0]], m[s[1]]); g(1, 5, 9, 13, m[s[2]], m[s[3]]);
+    g(2, 6, 10, 14, m[s[4]], m[s[5]]); g(3, 7, 11, 15, m[s[6]], m[s[7]]);
+    g(0, 5, 10, 15, m[s[8]], m[s[9]]); g(1, 6, 11, 12, m[s[10]], m[s[11]]);
+    g(2, 7, 8, 13, m[s[12]], m[s[13]]); g(3, 4, 9, 14, m[s[14]], m[s[15]]);
+  }
+  for (let i = 0; i < 8; i++) h[i] ^= v[i] ^ v[i + 8];
+}
+
+function blake2b(data, outLen) {
+  const h = IV.slice();
+  h[0] ^= 0x01010000n ^ BigInt(outLen);
+  let t = 0n;
+  let off = 0;
+  while (data.length - off > 128) {
+    t += 128n;
+    blake2bCompress(h, data.subarray(off, off + 128), t, false);
+    off += 128;
+  }
+  const block = Buffer.alloc(128);
+  data.copy(block, 0, off);
+  t += BigInt(data.length - off);
+  blake2bCompress(h, block, t, true);
+  const out = Buffer.alloc(64);
+  h.forEach((x, i) => out.writeBigUInt64LE(x, i * 8));
+  return out.subarray(0, outLen);
+}
+
+const le32 = (n) => { const b = Buffer.alloc(4); b.writeUInt32LE(n >>> 0); return b; };
+
+// hPrime is the variable-length hash H' of RFC 9106, section 3.3.
+function hPrime(x, len) {
+  const input = Buffer.concat([le32(len), x]);
+  if (len <= 64) return blake2b(input, len);
+  const r = Math.ceil(len / 32) - 2;
+  const parts = [];
+  let v = blake2b(input, 64);
+  parts.push(v.subarray(0, 32));
+  for (let i = 1; i < r; i++) {
+    v = blake2b(v, 64);
+    parts.push(v.subarray(0, 32));
+  }
+  parts.push(blake2b(v, len - 32 * r));
+  return Buffer.concat(parts);
+}
+
+const M32 = 0xffffffffn;
+
+function gb(v, a, b, c, d) {
+  const f = (x, y) => (x + y + 2n * (x & M32) * (y & M32)) & M64;
+  v[a] = f(v[a], v[b]); v[d] = rotr(v[d] ^ v[a], 32);
+  v[c] = f(v[c], v[d]); v[b] = rotr(v[b] ^ v[c], 24);
+  v[a] = f(v[a], v[b]); v[d] = rotr(v[d] ^ v[a], 16);
+  v[c] = f(v[c], v[d]); v[b] = rotr(v[b] ^ v[c], 63);
+}
+
+function permute(v, idx) {
+  const w = idx.map((i) => v[i]);
+  gb(w, 0, 4, 8, 12); gb(w, 1, 5, 9, 13); gb(w, 2, 6, 10, 14); gb(w, 3, 7, 11, 15);
+  gb(w, 0, 5, 10, 15); gb(w, 1, 6, 11, 12); gb(w, 2, 7, 8, 13); gb(w, 3, 4, 9, 14);
+  idx.forEach((i, k) => { v[i] = w[k]; });
+}
+
+// compress is the Argon2 block function G; old, if given, is XORed in as
+// version 1.3 does when overwriting blocks in later passes.
+function compress(x, y, old) {
+  const r = x.map((a, i) => a ^ y[i]);
+  const z = r.slice();
+  for (let i = 0; i < 8; i++) {
+    const idx = [];
+    for (let j = 0; j < 16; j++) idx.push(16 * i + j);
+    permute(z, idx);
+  }
+  for (let i = 0; i < 8; i++) {
+    const idx = [];
+    for (let j = 0; j < 8; j++) idx.push(2 * i + 16 * j, 2 * i + 16 * j + 1);
+    permute(z, idx);
+  }
+  return z.map((a, i) => a ^ r[i] ^ (old ? old[i] : 0n));
+}
+
+const toWords = (buf) => { const w = []; for (let i = 0; i < 128; i++) w.push(buf.readBigUInt64LE(i * 8)); return w; };
+const TYPES = { d: 0, i: 1, id: 2 };
+
+function argon2(type, password, salt, secret, ad, time, memory, lanes, tagLen) {
+  const y = TYPES[type];
+  const h0 = blake2b(Buffer.concat([
+    le32(lanes), le32(tagLen), le32(memory), le32(time), le32(0x13), le32(y),
+    le32(password.length), password, le32(salt.length), salt,
+    le32(secret.length), secret, le32(ad.length), ad,
+  ]), 64);
+
+  const blocks = 4 * lanes * Math.floor(memory / (4 * lanes));
+  const q = blocks / lanes;
+  const seg = q / 4;
+  const B = [];
+  for (let l = 0; l < lanes; l++) {
+    B.push(new Array(q));
+    B[l][0] = toWords(hPrime(Buffer.concat([h0, le32(0), le32(l)]), 1024));
+    B[l][1] = toWords(hPrime(Buffer.concat([h0, le32(1), le32(l)]), 1024));
+  }
+  const zero = new Array(128).fill(0n);
+
+  for (let pass = 0; pass < time; pass++) {
+    for (let slice = 0; slice < 4; slice++) {
+      for (let l = 0; l < lanes; l++) {
+        const independent = y === 1 || (y === 2 && pass === 0 && slice < 2);
+        const input = zero.slice();
+        let address = null;
+        input[0] = BigInt(pass); input[1] = BigInt(l); input[2] = BigInt(slice);
+        input[3] = BigInt(blocks); input[4] = BigInt(time); input[5] = BigInt(y);
+        const nextAddresses = () => {
+          input[6] += 1n;
+          address = compress(zero, compress(zero, input));
+        };
+
+        let start = 0;
+        if (pass === 0 && slice === 0) {
+          start = 2;
+          if (independent) nextAddresses();
+        }
+        for (let i = start; i < seg; i++) {
+          const col = slice * seg + i;
+          const prev = col === 0 ? q - 1 : col - 1;
+          let rand;
+          if (independent) {
+            if (i % 128 === 0) nextAddresses();
+            rand = address[i % 128];
+          } else {
+            rand = B[l][prev][0];
+          }
+          let refLane = Number((rand >> 32n) % BigInt(lanes));
+          if (pass === 0 && slice === 0) refLane = l;
+          const same = refLane === l;
+
+          let area;
+          if (pass === 0) {
+            if (slice === 0) area = i - 1;
+            else if (same) area = slice * seg + i - 1;
+            else area = slice * seg + (i === 0 ? -1 : 0);
+          } else if (same) {
+            area = q - seg + i - 1;
+          } else {
+            area = q - seg + (i === 0 ? -1 : 0);
+          }
+          let rel = rand & M32;
+          rel = (rel * rel) >> 32n;
+          rel = BigInt(area) - 1n - ((BigInt(area) * rel) >> 32n);
+          const startPos = pass === 0 || slice === 3 ? 0 : (slice + 1) * seg;
+          const ref = (startPos + Number(rel)) % q;
+
+          B[l][col] = compress(B[l][prev], B[refLane][ref], pass === 0 ? null : B[l][col]);
+        }
+      }
+    }
+  }
+
+  let c = B[0][q - 1];
+  for (let l = 1; l < lanes; l++) c = c.map((a, i) => a ^ B[l][q - 1][i]);
+  const cb = Buffer.alloc(1024);
+  c.forEach((x, i) => cb.writeBigUInt64LE(x, i * 8));
+  return hPrime(cb, tagLen);
+}
+
+// selfTest checks the RFC 9106 section 5 vectors before any fixture is made.
+function selfTest() {
+  const crypto = require('crypto');
+  if (!blake2b(Buffer.from('abc'), 64).equals(crypto.createHash('blake2b512').update('abc').digest())) {
+    throw new Error('blake2b self-test failed');
+  }
+  const args = [Buffer.alloc(32, 1), Buffer.alloc(16, 2), Buffer.alloc(8, 3), Buffer.alloc(12, 4), 3, 32, 4, 32];
+  const want = {
+    d: '512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb',
+    i: 'c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8',
+    id: '0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659',
+  };
+  for (const type of Object.keys(want)) {
+    const got = argon2(type, ...args).toString('hex');
+    if (got !== want[type]) throw new Error(`argon2${type} self-test failed: ${got}`);
+  }
+}
+
+module.exports = { argon2, selfTest };
@@ -0,0 +1,80 @@
+// Builds bitwarden_pbkdf2.json and bitwarden_argon2id.json, password
+// protected exports in the layout Bitwarden writes, without using any of
+// the Go code under test. Export password: "correct horse".
+'use strict';
+
+const crypto = require('crypto');
+const fs = require('fs');
+const { argon2, selfTest } = require('./argon2.js');
+
+const password = 'correct horse';
+
+const items = [
+  {
+    id: '5f4a6c0e-1b2d-4c3e-9f80-1a2b3c4d5e6f', organizationId: null, folderId: null,
+    type: 1, reprompt: 0, name: 'example.com', notes: 'first line\nsecond line', favorite: false,
+    fields: [
+      { name: 'PIN hint', value: 'birthday', type: 0, linkedId: null },
+      { name: 'Recovery', value: 'r3c0very', type: 1, linkedId: null },
+      { name: 'Username link', value: null, type: 3, linkedId: 100 },
+    ],
+    login: {
+      fido2Credentials: [],
+      uris: [{ match: null, uri: 'https://example.com/login' }, { match: null, uri: 'https://example.org' }],
+      username: 'alice', password: 'Tr0ub4dor&3', totp: 'otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=Example',
+      passwordRevisionDate: '2024-03-05T10:20:30.000Z',
+    },
+    collectionIds: null, revisionDate: '2024-06-01T08:00:00.000Z', creationDate: '2023-01-01T00:00:00.000Z', deletedDate: null,
+  },
+  {
+    id: '6a5b4c3d-2e1f-4a0b-8c9d-0e1f2a3b4c5d', organizationId: null, folderId: null,
+    type: 1, reprompt: 0, name: 'totp only', notes: null, favorite: false,
+    login: { fido2Credentials: [], uris: [], username: 'bob', password: null, totp: 'GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ' },
+    collectionIds: null, revisionDate: '2024-06-02T08:00:00.000Z', creationDate: '2024-06-02T08:00:00.000Z', deletedDate: null,
+  },
+  {
+    id: '7b6c5d4e-3f2a-4b1c-9d0e-1f2a3b4c5d6e', organizationId: null, folderId: null,
+    type: 2, reprompt: 0, name: 'wifi', notes: 'the password is on the router', favorite: false,
+    secureNote: { type: 0 },
+    collectionIds: null, revisionDate: '2024-06-03T08:00:00.000Z', creationDate: '2024-06-03T08:00:00.000Z', deletedDate: null,
+  },
+];
+
+function hkdfExpand(prk, info) {
+  return crypto.createHmac('sha256', prk).update(Buffer.concat([Buffer.from(info), Buffer.from([1])])).digest();
+}
+
+function encryptString(plaintext, encKey, macKey) {
+  const iv = crypto.randomBytes(16);
+  const cipher = crypto.createCipheriv('aes-256-cbc', encKey, iv);
+  const ct = Buffer.concat([cipher.update(plaintext, 'utf8'), cipher.final()]);
+  const mac = crypto.createHmac('sha256', macKey).update(Buffer.concat([iv, ct])).digest();
+  return `2.${iv.toString('base64')}|${ct.toString('base64')}|${mac.toString('base64')}`;
+}
+
+function makeExport(kdfType) {
+  const salt = crypto.randomBytes(16).toString('base64');
+  const out = {
+    encrypted: true, passwordProtected: true, salt, kdfType,
+    kdfIterations: 600000, kdfMemory: null, kdfParallelism: null,
+  };
+  let key;
+  if (kdfType === 0) {
+    key = crypto.pbkdf2Sync(password, salt, out.kdfIterations, 32, 'sha256');
+  } else {
+    Object.assign(out, { kdfIterations: 2, kdfMemory: 16, kdfParallelism: 1 });
+    const hashedSalt = crypto.createHash('sha256').update(salt).digest();
+    key = argon2('id', Buffer.from(password), hashedSalt, Buffer.alloc(0), Buffer.alloc(0),
+      out.kdfIterations, out.kdfMemory * 1024, out.kdfParallelism, 32);
+  }
+  const encKey = hkdfExpand(key, 'enc');
+  const macKey = hkdfExpand(key, 'mac');
+
+  out.encKeyValidation_DO_NOT_EDIT = encryptString(crypto.randomUUID(), encKey, macKey);
+  out.data = encryptString(JSON.stringify({ encrypted: false, folders: [], items }, null, 2), encKey, macKey);
+  return JSON.stringify(out, null, 2) + '\n';
+}
+
+selfTest();
+fs.writeFileSync('bitwarden_pbkdf2.json', makeExport(0));
+fs.writeFileSync('bitwarden_argon2id.json', makeExport(1));
@@ -0,0 +1,190 @@
+// Builds keepassxc_aeskdf.kdbx (AES-256 payload, AES-KDF) and
+// keepassxc_argon2d.kdbx (ChaCha20 payload, Argon2d), KDBX 4.0 databases
+// with the header fields and XML layout KeePassXC saves, without using any
+// of the Go code under test. Database password: "correct horse".
+'use strict';
+
+const crypto = require('crypto');
+const fs = require('fs');
+const zlib = require('zlib');
+const { argon2, selfTest } = require('./argon2.js');
+
+const password = 'correct horse';
+
+const CIPHER_AES = Buffer.from('31c1f2e6bf714350be5805216afc5aff', 'hex');
+const CIPHER_CHACHA20 = Buffer.from('d6038a2b8b6f4cb5a524339a31dbb59a', 'hex');
+const KDF_AES = Buffer.from('c9d9f39a628a4460bf740d08c18a4fea', 'hex');
+const KDF_ARGON2D = Buffer.from('ef636ddf8c29444b91f7a9a403e30a0c', 'hex');
+
+function u32(n) { const b = Buffer.alloc(4); b.writeUInt32LE(n); return b; }
+function u64(n) { const b = Buffer.alloc(8); b.writeBigUInt64LE(BigInt(n)); return b; }
+function sha256(...parts) { return crypto.createHash('sha256').update(Buffer.concat(parts)).digest(); }
+function sha512(...parts) { return crypto.createHash('sha512').update(Buffer.concat(parts)).digest(); }
+function hmac(key, ...parts) { return crypto.createHmac('sha256', key).update(Buffer.concat(parts)).digest(); }
+
+function variantDictionary(entries) {
+  const parts = [Buffer.from([0x00, 0x01])];
+  for (const [type, key, value] of entries) {
+    const k = Buffer.from(key);
+    parts.push(Buffer.from([type]), u32(k.length), k, u32(value.length), value);
+  }
+  parts.push(Buffer.from([0x00]));
+  return Buffer.concat(parts);
+}
+
+function headerField(id, data) {
+  return Buffer.concat([Buffer.from([id]), u32(data.length), data]);
+}
+
+// KDBX 4 times: base64 of the little-endian seconds since 0001-01-01 UTC.
+function kdbxTime(iso) {
+  return u64(Date.parse(iso) / 1000 + 62135596800).toString('base64');
+}
+
+function chacha20(key, nonce, data) {
+  const cipher = crypto.createCipheriv('chacha20', key, Buffer.concat([u32(0), nonce]));
+  return Buffer.concat([cipher.update(data), cipher.final()]);
+}
+
+function escapeXML(s) {
+  return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+function buildXML(streamKey) {
+  const hash = sha512(streamKey);
+  const keystream = chacha20(hash.subarray(0, 32), hash.subarray(32, 44), Buffer.alloc(4096));
+  let offset = 0;
+  const protect = (value) => {
+    const plain = Buffer.from(value);
+    const out = Buffer.alloc(plain.length);
+    for (let i = 0; i < plain.length; i++) out[i] = plain[i] ^ keystream[offset + i];
+    offset += plain.length;
+    return out.toString('base64');
+  };
+
+  const uuid = (n) => Buffer.alloc(16, n).toString('base64');
+  const times = (iso) => `<Times><LastModificationTime>${kdbxTime(iso)}</LastModificationTime>` +
+    `<CreationTime>${kdbxTime('2023-01-01T00:00:00Z')}</CreationTime><LastAccessTime>${kdbxTime(iso)}</LastAccessTime>` +
+    `<ExpiryTime>${kdbxTime(iso)}</ExpiryTime><Expires>False</Expires><UsageCount>0</UsageCount>` +
+    `<LocationChanged>${kdbxTime(iso)}</LocationChanged></Times>`;
+  // Protected values are filled in once the document is complete, since
+  // they take the inner stream in document order.
+  const protectedValues = [];
+  const string = (key, value, isProtected) => isProtected
+    ? `<String><Key>${key}</Key><Value Protected="True">@${protectedValues.push(value) - 1}@</Value></String>`
+    : `<String><Key>${key}</Key><Value>${escapeXML(value)}</Value></String>`;
+  const entry = (id, iso, strings, history = '') =>
+    `<Entry><UUID>${uuid(id)}</UUID><IconID>0</IconID><ForegroundColor/><BackgroundColor/><OverrideURL/><Tags/>` +
+    times(iso) + strings.map((s) => string(...s)).join('') +
+    `<AutoType><Enabled>True</Enabled><DataTransferObfuscation>0</DataTransferObfuscation><DefaultSequence/></AutoType>` +
+    `<History>${history}</History></Entry>`;
+  const group = (id, name, body) =>
+    `<Group><UUID>${uuid(id)}</UUID><Name>${name}</Name><Notes/><IconID>48</IconID>${times('2023-01-01T00:00:00Z')}` +
+    `<IsExpanded>True</IsExpanded><DefaultAutoTypeSequence/><EnableAutoType>null</EnableAutoType>` +
+    `<EnableSearching>null</EnableSearching><LastTopVisibleEntry>${uuid(0)}</LastTopVisibleEntry>${body}</Group>`;
+
+  const old = entry(1, '2022-05-01T00:00:00Z', [
+    ['Title', 'example.com'], ['UserName', 'alice'], ['Password', 'old-password', true],
+  ]);
+  const example = entry(1, '2024-03-05T10:20:30Z', [
+    ['Notes', 'first line\nsecond line'],
+    ['Password', 'Tr0ub4dor&3', true],
+    ['Security question', 'first pet', true],
+    ['Title', 'example.com'],
+    ['URL', 'https://example.com/login'],
+    ['UserName', 'alice'],
+    ['otp', 'otpauth://totp/example.com:alice?secret=JBSWY3DPEHPK3PXP&period=30&digits=6&issuer=example.com'],
+  ], old);
+  const wifi = entry(2, '2024-01-01T00:00:00Z', [
+    ['Notes', ''], ['Password', 'hunter22', true], ['Title', 'Wifi'], ['URL', ''], ['UserName', ''],
+  ]);
+  const forum = entry(3, '2024-02-02T02:02:02Z', [
+    ['Notes', ''], ['Password', 'correct-staple', true], ['TOTP Seed', 'JBSWY3DPEHPK3PXP', true],
+    ['TOTP Settings', '30;S'], ['Title', 'forum'], ['URL', 'https://forum.example'], ['UserName', 'bob'],
+  ]);
+  const deleted = entry(4, '2024-04-04T04:04:04Z', [
+    ['Notes', ''], ['Password', 'gone', true], ['Title', 'deleted'], ['URL', ''], ['UserName', 'carol'],
+  ]);
+
+  const root = group(10, 'Root', example + wifi +
+    group(11, 'Internet', group(12, 'Social', forum)) +
+    group(13, 'Recycle Bin', deleted));
+
+  const xml = '<?xml version="1.0" encoding="UTF-8" standalone="yes"?>\n<KeePassFile><Meta>' +
+    '<Generator>KeePassXC</Generator><DatabaseName>Fixture</DatabaseName>' +
+    `<DatabaseNameChanged>${kdbxTime('2023-01-01T00:00:00Z')}</DatabaseNameChanged><DatabaseDescription/>` +
+    '<MemoryProtection><ProtectTitle>False</ProtectTitle><ProtectUserName>False</ProtectUserName>' +
+    '<ProtectPassword>True</ProtectPassword><ProtectURL>False</ProtectURL><ProtectNotes>False</ProtectNotes></MemoryProtection>' +
+    `<RecycleBinEnabled>True</RecycleBinEnabled><RecycleBinUUID>${uuid(13)}</RecycleBinUUID>` +
+    `<HistoryMaxItems>10</HistoryMaxItems><HistoryMaxSize>6291456</HistoryMaxSize><CustomData/></Meta>` +
+    `<Root>${root}<DeletedObjects/></Root></KeePassFile>\n`;
+  return xml.replace(/@(\d+)@/g, (_, i) => protect(protectedValues[i]));
+}
+
+function makeKDBX(cipherID, kdf) {
+  const masterSeed = crypto.randomBytes(32);
+  const salt = crypto.randomBytes(32);
+  const composite = sha256(sha256(Buffer.from(password)));
+
+  let kdfParams, transformed;
+  if (kdf === 'aes') {
+    const rounds = 60000;
+    kdfParams = variantDictionary([[0x42, '$UUID', KDF_AES], [0x05, 'R', u64(rounds)], [0x42, 'S', salt]]);
+    const ecb = crypto.createCipheriv('aes-256-ecb', salt, null).setAutoPadding(false);
+    let key = composite;
+    for (let i = 0; i < rounds; i++) key = ecb.update(key);
+    transformed = sha256(key);
+  } else {
+    const iterations = 2, memory = 1024 * 1024, parallelism = 2;
+    kdfParams = variantDictionary([
+      [0x42, '$UUID', KDF_ARGON2D], [0x42, 'S', salt], [0x04, 'P', u32(parallelism)],
+      [0x05, 'M', u64(memory)], [0x05, 'I', u64(iterations)], [0x04, 'V', u32(0x13)],
+    ]);
+    transformed = argon2('d', composite, salt, Buffer.alloc(0), Buffer.alloc(0), iterations, memory / 1024, parallelism, 32);
+  }
+
+  const iv = crypto.randomBytes(cipherID === CIPHER_AES ? 16 : 12);
+  const header = Buffer.concat([
+    u32(0x9AA2D903), u32(0xB54BFB67), u32(0x00040000),
+    headerField(2, cipherID),
+    headerField(3, u32(1)),
+    headerField(4, masterSeed),
+    headerField(7, iv),
+    headerField(11, kdfParams),
+    headerField(0, Buffer.from('\r\n\r\n')),
+  ]);
+
+  const encKey = sha256(masterSeed, transformed);
+  const hmacBase = sha512(masterSeed, transformed, Buffer.from([1]));
+  const blockKey = (index) => sha512(u64(index), hmacBase);
+
+  const streamKey = crypto.randomBytes(64);
+  const inner = Buffer.concat([
+    headerField(1, u32(3)), headerField(2, streamKey), headerField(0, Buffer.alloc(0)),
+    Buffer.from(buildXML(streamKey)),
+  ]);
+  const compressed = zlib.gzipSync(inner);
+
+  let payload;
+  if (cipherID === CIPHER_AES) {
+    const aes = crypto.createCipheriv('aes-256-cbc', encKey, iv);
+    payload = Buffer.concat([aes.update(compressed), aes.final()]);
+  } else {
+    payload = chacha20(encKey, iv, compressed);
+  }
+
+  const block = (index, data) =>
+    Buffer.concat([hmac(blockKey(index), u64(index), u32(data.length), data), u32(data.length), data]);
+
+  return Buffer.concat([
+    header,
+    sha256(header),
+    hmac(blockKey(0xFFFFFFFFFFFFFFFFn), header),
+    block(0, payload),
+    block(1, Buffer.alloc(0)),
+  ]);
+}
+
+selfTest();
+fs.writeFileSync('keepassxc_aeskdf.kdbx', makeKDBX(CIPHER_AES, 'aes'));
+fs.writeFileSync('keepassxc_argon2d.kdbx', makeKDBX(CIPHER_CHACHA20, 'argon2d'));
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	watchBarWidth = 20
+
+	keyCtrlC     = 3
+	keyBackspace = 8
+	keyEnter     = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+type watchRow struct {
+	entry     MFAEntry
+	code      string
+	remaining int
+	err       error
+}
+
+type watchState struct {
+	entries  []MFAEntry
+	filter   string
+	selected int
+	status   string
+}
+
+func matchesMFAFilter(entry MFAEntry, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	return strings.Contains(strings.ToLower(entry.Account), filter) ||
+		strings.Contains(strings.ToLower(entry.Name), filter)
+}
+
+func (s *watchState) visibleRows(now time.Time) []watchRow {
+	var rows []watchRow
+	for _, entry := range s.entries {
+		if !matchesMFAFilter(entry, s.filter) {
+			continue
+		}
+		code, remaining, err := codeAt(entry, now)
+		rows = append(rows, watchRow{entry: entry, code: code, remaining: remaining, err: err})
+	}
+	return rows
+}
+
+func countdownBar(remaining, period int) string {
+	filled := 0
+	if period > 0 {
+		filled = remaining * watchBarWidth / period
+	}
+	return strings.Repeat("#", filled) + strings.Repeat("-", watchBarWidth-filled)
+}
+
+func formatWatchCode(code string) string {
+	if len(code) == 6 {
+		return code[:3] + " " + code[3:]
+	}
+	return code
+}
+
+// render draws the whole dashboard. Lines end in \r\n because the terminal
+// is in raw mode and won't translate a bare newline.
+func (s *watchState) render(rows []watchRow) {
+	var b strings.Builder
+
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString("MFA Watch - type to filter, Up/Down to select, Enter to copy, Esc to quit\r\n")
+	fmt.Fprintf(&b, "Filter: %s\r\n\r\n", s.filter)
+
+	if len(rows) == 0 {
+		b.WriteString("  No matching MFA entries\r\n")
+	}
+
+	for i, row := range rows {
+		var line string
+		if row.err != nil {
+			line = fmt.Sprintf("  %-20s %-32s error: %v", row.entry.Account, row.entry.Name, row.err)
+		} else {
+			line = fmt.Sprintf("  %-20s %-32s %-8s [%s] %2ds",
+				row.entry.Account, row.entry.Name, formatWatchCode(row.code),
+				countdownBar(row.remaining, row.entry.Period), row.remaining)
+		}
+		if i == s.selected {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	if s.status != "" {
+		fmt.Fprintf(&b, "\r\n%s\r\n", s.status)
+	}
+
+	os.Stdout.WriteString(b.String())
+}
+
+// handleKey applies a single read from the terminal to the state. It
+// returns false once the user asked to quit.
+func (s *watchState) handleKey(input []byte, rows []watchRow) bool {
+	if len(input) >= 3 && input[0] == keyEscape && input[1] == '[' {
+		switch input[2] {
+		case 'A':
+			if s.selected > 0 {
+				s.selected--
+			}
+		case 'B':
+			if s.selected < len(rows)-1 {
+				s.selected++
+			}
+		}
+		return true
+	}
+
+	for _, c := range input {
+		switch {
+		case c == keyCtrlC || c == keyEscape:
+			return false
+		case c == keyEnter:
+			if s.selected < len(rows) && rows[s.selected].err == nil {
+				row := rows[s.selected]
+				if err := copyToClipboard(row.code); err != nil {
+					s.status = fmt.Sprintf("Copy failed: %v", err)
+				} else {
+					s.status = fmt.Sprintf("Copied code for %s (%s)", row.entry.Name, row.entry.Account)
+				}
+			}
+		case c == keyBackspace || c == keyDelete:
+			if s.filter != "" {
+				s.filter = s.filter[:len(s.filter)-1]
+				s.selected = 0
+			}
+		case c == keyCtrlU:
+			s.filter = ""
+			s.selected = 0
+		case c >= 32 && c < 127:
+			s.filter += string(c)
+			s.selected = 0
+		}
+	}
+	return true
+}
+
+// WatchMFA shows a live, full-screen view of every MFA entry matching
+// filter until the user quits.
+func WatchMFA(filter string) error {
+	storage, err := loadMFAStorage()
+	if err != nil {
+		return err
+	}
+
+	if len(storage.Entries) == 0 {
+		return fmt.Errorf("no MFA entries found")
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("watch requires an interactive terminal")
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to switch terminal to raw mode: %v", err)
+	}
+	defer term.Restore(fd, oldState)
+
+	// Use the alternate screen so the dashboard doesn't clobber scrollback.
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	defer os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			input := make([]byte, n)
+			copy(input, buf[:n])
+			keys <- input
+		}
+	}()
+
+	state := &watchState{entries: storage.Entries, filter: filter}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	rows := state.visibleRows(time.Now())
+	state.render(rows)
+
+	for {
+		select {
+		case input, ok := <-keys:
+			if !ok || !state.handleKey(input, rows) {
+				return nil
+			}
+		case <-ticker.C:
+		}
+
+		rows = state.visibleRows(time.Now())
+		if state.selected >= len(rows) {
+			state.selected = 0
+		}
+		state.render(rows)
+	}
+}